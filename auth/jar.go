@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A cookie saved in the jar file
+type savedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"` // Empty for a cookie of the host only
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires"` // Zero for a cookie of the session only
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httponly,omitempty"`
+}
+
+func (c savedCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+/*
+Cookie jar that can be saved to a JSON file, to keep a session between runs.
+The cookiejar package only gives back the names and the values of the cookies,
+so the jar also keeps their attributes to save them
+*/
+type Jar struct {
+	jar     *cookiejar.Jar
+	cookies map[string]map[string]savedCookie // Cookies by site (scheme and host), then by domain, path and name
+	lock    sync.Mutex
+}
+
+/*
+Make an empty cookie jar
+*/
+func NewJar() *Jar {
+	jar, _ := cookiejar.New(nil)
+	return &Jar{jar: jar, cookies: map[string]map[string]savedCookie{}}
+}
+
+/*
+Load the cookie jar saved at `path`. Returns an empty jar if the file does not exist.
+The expired cookies are skipped
+*/
+func LoadJar(path string) (*Jar, error) {
+	j := NewJar()
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	saved := map[string][]savedCookie{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for site, cookies := range saved {
+		u, err := url.Parse(site)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*http.Cookie, 0, len(cookies))
+		for _, c := range cookies {
+			if c.expired(now) {
+				continue
+			}
+			// The jars saved before the attributes have neither a path nor a domain
+			if c.Path == "" {
+				c.Path = "/"
+			}
+			list = append(list, &http.Cookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, Expires: c.Expires, Secure: c.Secure, HttpOnly: c.HttpOnly})
+		}
+		j.SetCookies(u, list)
+	}
+	return j, nil
+}
+
+// Path of a cookie set without one, see RFC 6265 section 5.1.4
+func defaultCookiePath(u *url.URL) string {
+	i := strings.LastIndex(u.Path, "/")
+	if i <= 0 || !strings.HasPrefix(u.Path, "/") {
+		return "/"
+	}
+	return u.Path[:i]
+}
+
+// Implement http.CookieJar
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	site := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	if j.cookies[site] == nil {
+		j.cookies[site] = map[string]savedCookie{}
+	}
+	now := time.Now()
+	for _, c := range cookies {
+		saved := savedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if !strings.HasPrefix(saved.Path, "/") {
+			saved.Path = defaultCookiePath(u)
+		}
+		// Max-Age has priority over Expires
+		if c.MaxAge < 0 {
+			saved.Expires = now
+		} else if c.MaxAge > 0 {
+			saved.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		key := saved.Domain + ";" + saved.Path + ";" + saved.Name
+		if saved.expired(now) {
+			delete(j.cookies[site], key)
+		} else {
+			j.cookies[site][key] = saved
+		}
+	}
+	j.jar.SetCookies(u, cookies)
+}
+
+// Implement http.CookieJar
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// Whether no site has set cookies in the jar
+func (j *Jar) empty() bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	for _, cookies := range j.cookies {
+		if len(cookies) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Remove every cookie of the jar
+*/
+func (j *Jar) Clear() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.jar, _ = cookiejar.New(nil)
+	j.cookies = map[string]map[string]savedCookie{}
+}
+
+/*
+Save the cookies of the jar to `path`, with their domain, path, expiry and flags. The expired cookies are dropped.
+The file is only readable by the user, as the cookies give access to the account
+*/
+func (j *Jar) Save(path string) error {
+	j.lock.Lock()
+	now := time.Now()
+	saved := map[string][]savedCookie{}
+	for site, cookies := range j.cookies {
+		keys := make([]string, 0, len(cookies))
+		for key, c := range cookies {
+			if !c.expired(now) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			saved[site] = append(saved[site], cookies[key])
+		}
+	}
+	j.lock.Unlock()
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves a truncated jar
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
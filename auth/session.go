@@ -0,0 +1,476 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Types of the tokens of meta=tokens
+const (
+	TokenCSRF     = "csrf"
+	TokenLogin    = "login"
+	TokenWatch    = "watch"
+	TokenPatrol   = "patrol"
+	TokenRollback = "rollback"
+)
+
+// Token given by the API to the anonymous users
+const anonToken = "+\\"
+
+// How a session is authenticated
+const (
+	methodNone    = iota
+	methodCookies // Cookies saved by an earlier run, without the credentials
+	methodLogin
+	methodClientLogin
+	methodBearer
+)
+
+var (
+	// Session used by the write methods of the library, set by Session.Activate
+	Default *Session
+	// Returned by the write methods when no session is activated
+	ErrNoSession = errors.New("no authenticated session, use Session.Activate first")
+	// Returned when the session expired and cannot log in again, ex: with an expired OAuth token
+	ErrSessionExpired = errors.New("the session has expired")
+)
+
+// Error of a failed login
+type LoginError struct {
+	Result string // Result of action=login or status of action=clientlogin, ex: "Failed", "FAIL", "UI"
+	Reason string // Message of the API
+}
+
+func (err *LoginError) Error() string {
+	if err.Reason == "" {
+		return fmt.Sprintf("login failed: %v", err.Result)
+	}
+	return fmt.Sprintf("login failed: %v", err.Reason)
+}
+
+// An authenticated session of a wiki, with its cookies and tokens
+type Session struct {
+	Client     *http.Client // Client of the requests of the session, with the cookie jar and the bearer token
+	Jar        *Jar
+	Language   string // Language of the wiki. Use "" for utils.WikiLanguage
+	CookiePath string // JSON file where Save writes the cookies. Empty to keep them in memory
+	method     int
+	username   string
+	password   string
+	bearer     string
+	user       string
+	tokens     map[string]string
+	previous   *http.Client // utils.HTTPClient before Activate, restored by Deactivate
+	replaced   *Session     // Default before Activate, restored by Deactivate
+	lock       sync.Mutex
+}
+
+// Add the bearer token of the session to the requests
+type bearerTransport struct {
+	session *Session
+	base    http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.session.lock.Lock()
+	token := t.session.bearer
+	t.session.lock.Unlock()
+	if token == "" {
+		return t.base.RoundTrip(request)
+	}
+	// A RoundTripper must not modify the request
+	request = request.Clone(request.Context())
+	request.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(request)
+}
+
+/*
+Make an anonymous session. The cookies saved at `cookiePath` are loaded, if any,
+so a session saved with Save does not need to log in again. The write requests of a restored
+session assert that it is logged in, so they fail with ErrSessionExpired instead of being sent
+anonymously once the cookies have expired.
+
+Keyword arguments:
+
+* cookiePath: JSON file of the cookies. Use "" to keep the cookies in memory
+*/
+func NewSession(cookiePath string) (*Session, error) {
+	jar := NewJar()
+	if cookiePath != "" {
+		var err error
+		jar, err = LoadJar(cookiePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s := &Session{Jar: jar, CookiePath: cookiePath, tokens: map[string]string{}}
+	if !jar.empty() {
+		s.method = methodCookies
+	}
+	s.Client = &http.Client{
+		Timeout:   utils.HTTPClient.Timeout,
+		Jar:       jar,
+		Transport: &bearerTransport{session: s, base: http.DefaultTransport},
+	}
+	return s, nil
+}
+
+/*
+Log in with a bot password, made at Special:BotPasswords, using action=login.
+
+Keyword arguments:
+
+* username: Name of the bot password, ex: "Example@MyBot"
+
+* password: The bot password
+*/
+func (s *Session) Login(username string, password string) error {
+	if err := s.login(username, password); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.method, s.username, s.password, s.bearer = methodLogin, username, password, ""
+	return nil
+}
+
+func (s *Session) login(username string, password string) error {
+	token, err := s.loginToken()
+	if err != nil {
+		return err
+	}
+	res, err := s.Post(map[string]string{
+		"action":     "login",
+		"lgname":     username,
+		"lgpassword": password,
+		"lgtoken":    token,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Error.Code != "" {
+		return errors.New(res.Error.Info)
+	}
+	if res.Login.Result != "Success" {
+		return &LoginError{Result: res.Login.Result, Reason: res.Login.Reason}
+	}
+	s.loggedIn(res.Login.Username)
+	return nil
+}
+
+/*
+Log in with the main password of an account, using action=clientlogin.
+Accounts with two-factor authentication cannot log in this way, use a bot password or OAuth instead
+*/
+func (s *Session) ClientLogin(username string, password string) error {
+	if err := s.clientLogin(username, password); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.method, s.username, s.password, s.bearer = methodClientLogin, username, password, ""
+	return nil
+}
+
+func (s *Session) clientLogin(username string, password string) error {
+	token, err := s.loginToken()
+	if err != nil {
+		return err
+	}
+	res, err := s.Post(map[string]string{
+		"action":         "clientlogin",
+		"username":       username,
+		"password":       password,
+		"logintoken":     token,
+		"loginreturnurl": s.apiURL(),
+	})
+	if err != nil {
+		return err
+	}
+	if res.Error.Code != "" {
+		return errors.New(res.Error.Info)
+	}
+	if res.ClientLogin.Status != "PASS" {
+		return &LoginError{Result: res.ClientLogin.Status, Reason: res.ClientLogin.Message}
+	}
+	s.loggedIn(res.ClientLogin.Username)
+	return nil
+}
+
+/*
+Authenticate the requests with an OAuth 2 access token, ex: an owner-only consumer token.
+The cookies are not used to authenticate the session anymore
+*/
+func (s *Session) SetBearerToken(token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.method, s.username, s.password, s.bearer = methodBearer, "", "", token
+	s.user = ""
+	s.tokens = map[string]string{}
+}
+
+// Get a login token. The login tokens are never reused
+func (s *Session) loginToken() (string, error) {
+	res, err := s.Post(map[string]string{"action": "query", "meta": "tokens", "type": TokenLogin})
+	if err != nil {
+		return "", err
+	}
+	if res.Error.Code != "" {
+		return "", errors.New(res.Error.Info)
+	}
+	token := res.Query.Tokens[TokenLogin+"token"]
+	if token == "" {
+		return "", errors.New("unable to get a login token")
+	}
+	return token, nil
+}
+
+func (s *Session) loggedIn(user string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.user = user
+	s.tokens = map[string]string{}
+}
+
+/*
+Name of the logged in user. Empty for an anonymous session, or before the first request of an OAuth session
+*/
+func (s *Session) User() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.user
+}
+
+/*
+Ask the API which user the session is logged in as, with meta=userinfo. The ID is 0 for an anonymous session
+*/
+func (s *Session) UserInfo() (models.InnerUserInfo, error) {
+	res, err := s.Post(map[string]string{"action": "query", "meta": "userinfo", "uiprop": "groups|rights"})
+	if err != nil {
+		return models.InnerUserInfo{}, err
+	}
+	if res.Error.Code != "" {
+		return models.InnerUserInfo{}, errors.New(res.Error.Info)
+	}
+	if res.Query.UserInfo.ID != 0 {
+		s.lock.Lock()
+		s.user = res.Query.UserInfo.Name
+		s.lock.Unlock()
+	}
+	return res.Query.UserInfo, nil
+}
+
+/*
+Get a token of the session with meta=tokens. The tokens are kept until they are rejected or the session logs in again.
+
+Keyword arguments:
+
+* kind: Type of the token, ex: TokenCSRF
+*/
+func (s *Session) Token(kind string) (string, error) {
+	s.lock.Lock()
+	token, ok := s.tokens[kind]
+	s.lock.Unlock()
+	if ok {
+		return token, nil
+	}
+	res, err := s.Post(map[string]string{"action": "query", "meta": "tokens", "type": kind})
+	if err != nil {
+		return "", err
+	}
+	if res.Error.Code != "" {
+		return "", errors.New(res.Error.Info)
+	}
+	token = res.Query.Tokens[kind+"token"]
+	if token == "" {
+		return "", fmt.Errorf("unable to get a %v token", kind)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens[kind] = token
+	return token, nil
+}
+
+/*
+Forget the tokens of the session, so they are fetched again
+*/
+func (s *Session) ClearTokens() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens = map[string]string{}
+}
+
+/*
+Log in again with the saved credentials, after the session has expired.
+
+Returns ErrSessionExpired for the OAuth, the restored and the anonymous sessions
+*/
+func (s *Session) Relogin() error {
+	s.lock.Lock()
+	method, username, password := s.method, s.username, s.password
+	s.lock.Unlock()
+	switch method {
+	case methodLogin:
+		return s.login(username, password)
+	case methodClientLogin:
+		return s.clientLogin(username, password)
+	}
+	return ErrSessionExpired
+}
+
+/*
+Send a POST request to the API of the wiki of the session. The result is never cached.
+A utils.LangArg argument sends the request to another language edition
+*/
+func (s *Session) Post(args map[string]string) (models.RequestResult, error) {
+	args = utils.CopyMap(args)
+	if _, ok := args[utils.LangArg]; !ok && s.Language != "" {
+		args[utils.LangArg] = s.Language
+	}
+	return utils.PostWikiApi(s.Client, args)
+}
+
+/*
+Same as Post, but send `args` and `file` as a multipart form, for the uploads.
+The file may take longer than the timeout of the client to send, so the timeout does not apply
+*/
+func (s *Session) PostFile(args map[string]string, file utils.FormFile) (models.RequestResult, error) {
+	args = utils.CopyMap(args)
+	if _, ok := args[utils.LangArg]; !ok && s.Language != "" {
+		args[utils.LangArg] = s.Language
+	}
+	return utils.PostMultipartWikiApi(utils.TransferClient(s.Client), args, file)
+}
+
+/*
+Send a POST request that needs a token, ex: an edit. The token of type `kind` is added as the "token" argument.
+
+When the session is logged in, the request asserts it. If the API answers that the session has expired,
+the session logs in again and the request is sent once more. The same happens with a new token if the token is rejected.
+
+Like utils.WikiRequester, the API errors are left in the result
+*/
+func (s *Session) PostWithToken(kind string, args map[string]string) (models.RequestResult, error) {
+	return s.postWithToken(kind, args, s.Post)
+}
+
+/*
+Same as PostWithToken, but send `args` and `file` as a multipart form, for the uploads
+*/
+func (s *Session) PostFileWithToken(kind string, args map[string]string, file utils.FormFile) (models.RequestResult, error) {
+	return s.postWithToken(kind, args, func(args map[string]string) (models.RequestResult, error) {
+		return s.PostFile(args, file)
+	})
+}
+
+func (s *Session) postWithToken(kind string, args map[string]string, post func(map[string]string) (models.RequestResult, error)) (models.RequestResult, error) {
+	args = utils.CopyMap(args)
+	s.lock.Lock()
+	method := s.method
+	s.lock.Unlock()
+	if method != methodNone {
+		args["assert"] = "user"
+	}
+	relogged, retoken := false, false
+	for {
+		token, err := s.Token(kind)
+		if err != nil {
+			return models.RequestResult{}, err
+		}
+		// The anonymous token of a logged in session means the session is lost
+		if method != methodNone && token == anonToken && !relogged {
+			relogged = true
+			if err := s.Relogin(); err != nil {
+				return models.RequestResult{}, err
+			}
+			continue
+		}
+		args["token"] = token
+		res, err := post(args)
+		if err != nil {
+			return models.RequestResult{}, err
+		}
+		switch {
+		case res.Error.Code == "badtoken" && !retoken:
+			retoken = true
+			s.ClearTokens()
+		case strings.HasPrefix(res.Error.Code, "assert") && !relogged:
+			relogged = true
+			if err := s.Relogin(); err != nil {
+				return models.RequestResult{}, err
+			}
+		default:
+			return res, nil
+		}
+	}
+}
+
+/*
+Log out and forget the credentials and the cookies of the session
+*/
+func (s *Session) Logout() error {
+	res, err := s.PostWithToken(TokenCSRF, map[string]string{"action": "logout"})
+	if err != nil {
+		return err
+	}
+	if res.Error.Code != "" {
+		return errors.New(res.Error.Info)
+	}
+	s.lock.Lock()
+	s.method, s.username, s.password, s.bearer = methodNone, "", "", ""
+	s.user = ""
+	s.tokens = map[string]string{}
+	s.lock.Unlock()
+	s.Jar.Clear()
+	return s.Save()
+}
+
+/*
+Save the cookies of the session to CookiePath. Does nothing without a CookiePath
+*/
+func (s *Session) Save() error {
+	if s.CookiePath == "" {
+		return nil
+	}
+	return s.Jar.Save(s.CookiePath)
+}
+
+/*
+Use the session for every request of the library: the read requests are sent with its cookies
+and its bearer token, which gives the higher limits of the bot accounts, and the write methods use it.
+Call Deactivate to go back to the previous utils.HTTPClient
+*/
+func (s *Session) Activate() {
+	if Default == s {
+		return
+	}
+	s.previous, s.replaced = utils.HTTPClient, Default
+	utils.HTTPClient = s.Client
+	Default = s
+}
+
+/*
+Stop using the session: utils.HTTPClient and Default are restored as they were before Activate.
+Does nothing if the session is not the activated one
+*/
+func (s *Session) Deactivate() {
+	if Default != s {
+		return
+	}
+	utils.HTTPClient, Default = s.previous, s.replaced
+	s.previous, s.replaced = nil, nil
+}
+
+func (s *Session) apiURL() string {
+	lang := s.Language
+	if lang == "" {
+		lang = utils.WikiLanguage
+	}
+	return fmt.Sprintf(utils.WikiURL, lang)
+}
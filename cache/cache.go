@@ -3,6 +3,8 @@ package cache
 import (
 	"crypto/sha256"
 	"errors"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/trietmn/go-wiki/models"
@@ -30,21 +32,26 @@ Create a cache that store:
 
 - Key: API request URL
 
-- Value: RequestResponse
+- Value: RequestResponse, or the raw body for the requests outside of api.php
 */
 func MakeWikiCache() WikiCache {
-	res := WikiCache{}
-	res.Memory = map[string]models.RequestResult{}
-	res.HashedKeyQueue = make([]string, 0, MaxCacheMemory)
-	res.CreatedTime = map[string]time.Time{}
-	return res
+	return WikiCache{
+		Memory:         map[string]models.RequestResult{},
+		RawMemory:      map[string][]byte{},
+		HashedKeyQueue: make([]string, 0, MaxCacheMemory),
+		CreatedTime:    map[string]time.Time{},
+	}
 }
 
-// Cache to store Wikipedia request result
+// Cache to store Wikipedia request result. Safe for concurrent use
 type WikiCache struct {
 	Memory         map[string]models.RequestResult // Map store request result
+	RawMemory      map[string][]byte               // Map store raw response body
 	HashedKeyQueue []string                        // Key queue. Delete the first item if reach max cache
 	CreatedTime    map[string]time.Time            // Map store created time
+	TitleKeys      map[string][]string             // Hashed keys of the requests about each page title
+	KeyTitles      map[string][]string             // Page titles of each hashed key, to prune TitleKeys
+	lock           sync.Mutex
 }
 
 // Hash a string into SHA256
@@ -55,30 +62,60 @@ func HashCacheKey(s string) string {
 }
 
 // Get WikiCache current number of cache
-func (cache WikiCache) GetLen() int {
+func (cache *WikiCache) GetLen() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
 	return len(cache.HashedKeyQueue)
 }
 
 // Add cache into the WikiCache
 func (cache *WikiCache) Add(s string, res models.RequestResult) {
-	if len(cache.Memory) >= MaxCacheMemory {
-		cache.Pop()
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if len(cache.HashedKeyQueue) >= MaxCacheMemory {
+		cache.pop()
 	}
 	key := HashCacheKey(s)
+	cache.init()
+	if _, ok := cache.Memory[key]; !ok {
+		cache.Memory[key] = res
+		cache.CreatedTime[key] = time.Now()
+		cache.HashedKeyQueue = append(cache.HashedKeyQueue, key)
+	}
+}
+
+// Add a raw response body into the WikiCache
+func (cache *WikiCache) AddRaw(s string, body []byte) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if len(cache.HashedKeyQueue) >= MaxCacheMemory {
+		cache.pop()
+	}
+	key := HashCacheKey(s)
+	cache.init()
+	if _, ok := cache.RawMemory[key]; !ok {
+		cache.RawMemory[key] = body
+		cache.CreatedTime[key] = time.Now()
+		cache.HashedKeyQueue = append(cache.HashedKeyQueue, key)
+	}
+}
+
+// Make the maps of a cleared cache
+func (cache *WikiCache) init() {
 	if cache.Memory == nil {
 		cache.Memory = map[string]models.RequestResult{}
 		cache.CreatedTime = map[string]time.Time{}
 		cache.HashedKeyQueue = make([]string, 0, MaxCacheMemory)
 	}
-	if _, ok := cache.Memory[key]; !ok {
-		cache.Memory[key] = res
-		cache.CreatedTime[key] = time.Now()
-		cache.HashedKeyQueue = append(cache.HashedKeyQueue, key)
+	if cache.RawMemory == nil {
+		cache.RawMemory = map[string][]byte{}
 	}
 }
 
 // Get response from the Cache
 func (cache *WikiCache) Get(s string) (models.RequestResult, error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
 	key := HashCacheKey(s)
 	if value, ok := cache.Memory[key]; ok {
 		if time.Since(cache.CreatedTime[key]) <= CacheExpiration {
@@ -86,26 +123,128 @@ func (cache *WikiCache) Get(s string) (models.RequestResult, error) {
 			cache.HashedKeyQueue = append(cache.HashedKeyQueue, key)
 			return value, nil
 		} else {
-			cache.HashedKeyQueue = FindAndDel(cache.HashedKeyQueue, key)
-			delete(cache.Memory, key)
+			cache.remove(key)
 			return models.RequestResult{}, errors.New("the data is outdated")
 		}
 	}
 	return models.RequestResult{}, errors.New("cache key not exist")
 }
 
+// Get a raw response body from the Cache
+func (cache *WikiCache) GetRaw(s string) ([]byte, error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	key := HashCacheKey(s)
+	if value, ok := cache.RawMemory[key]; ok {
+		if time.Since(cache.CreatedTime[key]) <= CacheExpiration {
+			cache.HashedKeyQueue = FindAndDel(cache.HashedKeyQueue, key)
+			cache.HashedKeyQueue = append(cache.HashedKeyQueue, key)
+			return value, nil
+		} else {
+			cache.remove(key)
+			return nil, errors.New("the data is outdated")
+		}
+	}
+	return nil, errors.New("cache key not exist")
+}
+
 // Delete the first key in the Cache
 func (cache *WikiCache) Pop() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.pop()
+}
+
+func (cache *WikiCache) pop() {
 	if len(cache.HashedKeyQueue) == 0 {
 		return
 	}
-	delete(cache.Memory, cache.HashedKeyQueue[0])
-	cache.HashedKeyQueue = cache.HashedKeyQueue[1:]
+	cache.remove(cache.HashedKeyQueue[0])
+}
+
+// Normalize a title for the TitleKeys map
+func titleKey(title string) string {
+	return strings.ReplaceAll(title, "_", " ")
+}
+
+// Record that the request s is about the pages `titles`, to be able to invalidate it with InvalidateTitle
+func (cache *WikiCache) Tag(s string, titles ...string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	key := HashCacheKey(s)
+	if _, ok := cache.CreatedTime[key]; !ok {
+		// The request is not cached, ex: made with NoCacheArg
+		return
+	}
+	if cache.TitleKeys == nil {
+		cache.TitleKeys = map[string][]string{}
+		cache.KeyTitles = map[string][]string{}
+	}
+	for _, title := range titles {
+		t := titleKey(title)
+		if contains(cache.KeyTitles[key], t) {
+			continue
+		}
+		cache.TitleKeys[t] = append(cache.TitleKeys[t], key)
+		cache.KeyTitles[key] = append(cache.KeyTitles[key], t)
+	}
+}
+
+// Delete every request about the page `title` from the Cache. Return the number of deleted requests
+func (cache *WikiCache) InvalidateTitle(title string) int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	t := titleKey(title)
+	// remove edits TitleKeys[t] in place
+	keys := append([]string{}, cache.TitleKeys[t]...)
+	count := 0
+	for _, key := range keys {
+		if cache.remove(key) {
+			count++
+		}
+	}
+	delete(cache.TitleKeys, t)
+	return count
+}
+
+// Delete a hashed key from the Cache, and from the titles of TitleKeys. Return false if it does not exist
+func (cache *WikiCache) remove(key string) bool {
+	for _, t := range cache.KeyTitles[key] {
+		if contains(cache.TitleKeys[t], key) {
+			cache.TitleKeys[t] = FindAndDel(cache.TitleKeys[t], key)
+		}
+		if len(cache.TitleKeys[t]) == 0 {
+			delete(cache.TitleKeys, t)
+		}
+	}
+	delete(cache.KeyTitles, key)
+	if _, ok := cache.CreatedTime[key]; !ok {
+		return false
+	}
+	delete(cache.Memory, key)
+	delete(cache.RawMemory, key)
+	delete(cache.CreatedTime, key)
+	cache.HashedKeyQueue = FindAndDel(cache.HashedKeyQueue, key)
+	return true
+}
+
+func contains(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 // Clear the whole Cache
 func (cache *WikiCache) Clear() {
-	*cache = WikiCache{}
-	// This line to avoid declare but not used error
-	_ = cache
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.Memory = nil
+	cache.RawMemory = nil
+	cache.HashedKeyQueue = nil
+	cache.CreatedTime = nil
+	cache.TitleKeys = nil
+	cache.KeyTitles = nil
 }
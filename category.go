@@ -0,0 +1,213 @@
+package gowiki
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Types of category members
+const (
+	CategoryMemberPage   = "page"
+	CategoryMemberSubcat = "subcat"
+	CategoryMemberFile   = "file"
+)
+
+// Namespace of the category pages
+const CategoryNamespace = 14
+
+// Filters and order of a category member listing
+type CategoryMembersOptions struct {
+	Types      []string // Any of CategoryMemberPage, CategoryMemberSubcat and CategoryMemberFile. Empty for all
+	Namespaces []int    // Namespaces of the members. Empty for all
+	Sort       string   // "sortkey" (default) or "timestamp"
+	Descending bool
+	Limit      int // Max number of members. Use 0 for all of them
+}
+
+// A page, subcategory or file of a category
+type CategoryMember struct {
+	PageID        int       `json:"pageid"`
+	Ns            int       `json:"ns"`
+	Title         string    `json:"title"`
+	Type          string    `json:"type"`
+	SortKeyPrefix string    `json:"sortkeyprefix"`
+	Timestamp     time.Time `json:"timestamp"` // When the member was added to the category
+	Category      string    `json:"category"`  // The category listing the member
+}
+
+/*
+Add the "Category:" prefix to a category name if it is missing
+*/
+func CategoryTitle(category string) string {
+	if strings.HasPrefix(strings.ToLower(category), "category:") {
+		return category
+	}
+	return "Category:" + category
+}
+
+/*
+Get the members of a category.
+
+Keyword arguments:
+
+* category: The category name, with or without the "Category:" prefix
+
+* opts: The filters and the order of the members
+
+Return:
+
+* List of the members of the category
+
+* Error
+*/
+func GetCategoryMembers(category string, opts CategoryMembersOptions) ([]CategoryMember, error) {
+	category = CategoryTitle(category)
+	args := map[string]string{
+		"action":  "query",
+		"list":    "categorymembers",
+		"cmtitle": category,
+		"cmprop":  "ids|title|type|sortkeyprefix|timestamp",
+		"cmlimit": "max",
+	}
+	if len(opts.Types) > 0 {
+		args["cmtype"] = strings.Join(opts.Types, "|")
+	}
+	if len(opts.Namespaces) > 0 {
+		args["cmnamespace"] = page.JoinNamespaces(opts.Namespaces)
+	}
+	if opts.Sort != "" {
+		args["cmsort"] = opts.Sort
+	}
+	if opts.Descending {
+		args["cmdir"] = "desc"
+	}
+	// The API ignores cmtype when sorting by timestamp, so the types are filtered here
+	filterTypes := len(opts.Types) > 0 && opts.Sort == "timestamp"
+	if opts.Limit > 0 && opts.Limit < 500 && !filterTypes {
+		args["cmlimit"] = strconv.Itoa(opts.Limit)
+	}
+
+	members := []CategoryMember{}
+	err := utils.ContinueQuery(args, func(res models.RequestResult) bool {
+		for _, v := range res.Query.CategoryMembers {
+			if filterTypes && !utils.Isin(opts.Types, v.Type) {
+				continue
+			}
+			timestamp, _ := time.Parse(time.RFC3339, v.Timestamp)
+			members = append(members, CategoryMember{
+				PageID:        v.PageID,
+				Ns:            v.Ns,
+				Title:         v.Title,
+				Type:          v.Type,
+				SortKeyPrefix: v.SortKeyPrefix,
+				Timestamp:     timestamp,
+				Category:      category,
+			})
+			if opts.Limit > 0 && len(members) >= opts.Limit {
+				return false
+			}
+		}
+		return true
+	})
+	return members, err
+}
+
+// Traverse a category tree, level by level
+type CategoryWalker struct {
+	MaxDepth int                    // Depth of the subcategories to expand. 0 only lists the category itself, -1 has no limit
+	Members  CategoryMembersOptions // Filters of the members passed to the visit function
+	Visited  map[string]bool        // Titles already visited. Shared by successive walks to skip pages seen before
+}
+
+/*
+Make a CategoryWalker expanding subcategories up to maxDepth levels
+*/
+func MakeCategoryWalker(maxDepth int, opts CategoryMembersOptions) *CategoryWalker {
+	return &CategoryWalker{MaxDepth: maxDepth, Members: opts, Visited: map[string]bool{}}
+}
+
+/*
+Walk the tree of `category` breadth first and call `visit` with every member not visited yet,
+with its depth (0 for the members of `category`). Return false from `visit` to stop the walk.
+
+Each category is listed once, so cycles in the category graph are harmless
+*/
+func (walker *CategoryWalker) Walk(category string, visit func(member CategoryMember, depth int) bool) error {
+	if walker.Visited == nil {
+		walker.Visited = map[string]bool{}
+	}
+	// Subcategories are always listed, to be able to go down the tree
+	opts := walker.Members
+	opts.Limit = 0
+	if len(opts.Types) > 0 && !utils.Isin(opts.Types, CategoryMemberSubcat) {
+		opts.Types = append(append([]string{}, opts.Types...), CategoryMemberSubcat)
+	}
+	if len(opts.Namespaces) > 0 {
+		opts.Namespaces = append(append([]int{}, opts.Namespaces...), CategoryNamespace)
+	}
+
+	category = CategoryTitle(category)
+	walker.Visited[category] = true
+	queue := []string{category}
+	for depth := 0; len(queue) > 0 && (walker.MaxDepth < 0 || depth <= walker.MaxDepth); depth++ {
+		next := []string{}
+		for _, c := range queue {
+			members, err := GetCategoryMembers(c, opts)
+			if err != nil {
+				return err
+			}
+			for _, m := range members {
+				if walker.Visited[m.Title] {
+					continue
+				}
+				walker.Visited[m.Title] = true
+				if m.Type == CategoryMemberSubcat {
+					next = append(next, m.Title)
+				}
+				if !walker.match(m) {
+					continue
+				}
+				if !visit(m, depth) {
+					return nil
+				}
+			}
+		}
+		queue = next
+	}
+	return nil
+}
+
+// Return true if the member passes the filters of the walker
+func (walker *CategoryWalker) match(m CategoryMember) bool {
+	if len(walker.Members.Types) > 0 && !utils.Isin(walker.Members.Types, m.Type) {
+		return false
+	}
+	if len(walker.Members.Namespaces) > 0 {
+		for _, ns := range walker.Members.Namespaces {
+			if ns == m.Ns {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+/*
+Get every article of a category tree, down to `maxDepth` levels of subcategories (-1 for no limit).
+Every article is returned once, even if it is in several categories of the tree
+*/
+func GetCategoryTreeArticles(category string, maxDepth int) ([]CategoryMember, error) {
+	walker := MakeCategoryWalker(maxDepth, CategoryMembersOptions{Types: []string{CategoryMemberPage}, Namespaces: []int{0}})
+	result := []CategoryMember{}
+	err := walker.Walk(category, func(member CategoryMember, depth int) bool {
+		result = append(result, member)
+		return true
+	})
+	return result, err
+}
@@ -0,0 +1,262 @@
+package eventstreams
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trietmn/go-wiki/utils"
+)
+
+var (
+	// Base URL of the EventStreams service. Point it to a local server in tests
+	BaseURL string = "https://stream.wikimedia.org/v2/stream"
+)
+
+const (
+	DefaultReconnectDelay = 3 * time.Second
+	DefaultBufferSize     = 100
+)
+
+// Streams to consume and filters of the events
+type Options struct {
+	Streams        []string      // Any of StreamRecentChange, StreamPageCreate and StreamRevisionCreate
+	Wikis          []string      // Only keep the events of these wikis, ex: "enwiki", or domains, ex: "en.wikipedia.org". Empty for all
+	Namespaces     []int         // Only keep the events of pages in these namespaces. Empty for all
+	LastEventID    string        // Resume after this event
+	Since          time.Time     // Start at this time when LastEventID is empty. Zero for now
+	BufferSize     int           // Number of events buffered before the client stops reading the stream. Use 0 for DefaultBufferSize
+	ReconnectDelay time.Duration // Wait before reconnecting. Use 0 for DefaultReconnectDelay
+	Client         *http.Client  // Client of the connection. Use nil for a client without timeout
+}
+
+// Consume EventStreams with Server-Sent Events, reconnecting when the connection drops
+type Client struct {
+	opts        Options
+	lastEventID string // ID of the last event received from the channel
+	readID      string // ID of the last event read from the connection, used to reconnect
+	err         error
+	lock        sync.Mutex
+}
+
+/*
+Make a client of the EventStreams service
+*/
+func NewClient(opts Options) *Client {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.ReconnectDelay <= 0 {
+		opts.ReconnectDelay = DefaultReconnectDelay
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+	return &Client{opts: opts, lastEventID: opts.LastEventID, readID: opts.LastEventID}
+}
+
+/*
+ID of the last event received from the channel of Subscribe. Save it once that event is handled,
+to resume the stream later with Options.LastEventID. The events waiting in the channel are not counted,
+so they are sent again after a resume. Event.ID gives the same position for each event
+*/
+func (client *Client) LastEventID() string {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	return client.lastEventID
+}
+
+/*
+Last connection or decoding error. The client reconnects after each of them
+*/
+func (client *Client) Err() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	return client.err
+}
+
+func (client *Client) setErr(err error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	client.err = err
+}
+
+/*
+Deliver the events on a channel until the context is done.
+
+The connection is only read when there is room in the channel, so a slow reader
+slows down the stream instead of losing events
+*/
+func (client *Client) Subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, client.opts.BufferSize)
+	out := make(chan Event)
+	// Forward the buffered events, the resume ID only moves once an event is received
+	go func() {
+		defer close(out)
+		for event := range ch {
+			select {
+			case out <- event:
+				if event.ID != "" {
+					client.lock.Lock()
+					client.lastEventID = event.ID
+					client.lock.Unlock()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(ch)
+		for {
+			err := client.consume(ctx, ch)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				client.setErr(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(client.opts.ReconnectDelay):
+			}
+		}
+	}()
+	return out
+}
+
+// ID of the last event read from the connection
+func (client *Client) lastReadID() string {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	return client.readID
+}
+
+// URL of the streams
+func (client *Client) url() string {
+	u := strings.TrimRight(BaseURL, "/") + "/" + strings.Join(client.opts.Streams, ",")
+	if client.lastReadID() == "" && !client.opts.Since.IsZero() {
+		u += "?since=" + url.QueryEscape(client.opts.Since.UTC().Format(time.RFC3339))
+	}
+	return u
+}
+
+// Read one connection until it ends
+func (client *Client) consume(ctx context.Context, ch chan<- Event) error {
+	request, err := utils.NewRequest("GET", client.url(), nil)
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "text/event-stream")
+	if id := client.lastReadID(); id != "" {
+		request.Header.Set("Last-Event-ID", id)
+	}
+	utils.WaitRateLimit()
+	res, err := client.opts.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("unable to connect to the stream, status %v", res.StatusCode)
+	}
+
+	reader := bufio.NewReader(res.Body)
+	var id string
+	var data bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			// End of a message
+			if data.Len() > 0 {
+				if !client.dispatch(ctx, ch, id, data.Bytes()) {
+					return nil
+				}
+			}
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			id = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				client.lock.Lock()
+				client.opts.ReconnectDelay = time.Duration(ms) * time.Millisecond
+				client.lock.Unlock()
+			}
+		}
+	}
+}
+
+// Decode, filter and send an event. Returns false if the context is done
+func (client *Client) dispatch(ctx context.Context, ch chan<- Event, id string, data []byte) bool {
+	event, err := decodeEvent(id, append([]byte{}, data...))
+	if id != "" {
+		client.lock.Lock()
+		client.readID = id
+		client.lock.Unlock()
+	}
+	if err != nil {
+		client.setErr(err)
+		return true
+	}
+	if !client.match(event) {
+		return true
+	}
+	select {
+	case ch <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Return true if the event passes the filters
+func (client *Client) match(event Event) bool {
+	if len(client.opts.Wikis) > 0 && !utils.Isin(client.opts.Wikis, event.Wiki()) && !utils.Isin(client.opts.Wikis, event.Meta.Domain) {
+		return false
+	}
+	if len(client.opts.Namespaces) > 0 {
+		ns, ok := event.Namespace()
+		if !ok {
+			return false
+		}
+		for _, n := range client.opts.Namespaces {
+			if n == ns {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package eventstreams
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Streams of the Wikimedia EventStreams service
+const (
+	StreamRecentChange   = "recentchange"
+	StreamPageCreate     = "page-create"
+	StreamRevisionCreate = "revision-create"
+)
+
+// An event of a stream. RecentChange or Revision is set depending on the stream
+type Event struct {
+	ID           string          `json:"id"` // SSE event ID, used to resume the stream
+	Stream       string          `json:"stream"`
+	Meta         Meta            `json:"meta"`
+	RecentChange *RecentChange   `json:"recentchange"` // StreamRecentChange events
+	Revision     *RevisionEvent  `json:"revision"`     // StreamPageCreate and StreamRevisionCreate events
+	Data         json.RawMessage `json:"data"`         // The raw JSON of the event
+}
+
+// Metadata shared by every event
+type Meta struct {
+	URI       string    `json:"uri"`
+	RequestID string    `json:"request_id"`
+	ID        string    `json:"id"`
+	DT        time.Time `json:"dt"`
+	Domain    string    `json:"domain"` // ex: "en.wikipedia.org"
+	Stream    string    `json:"stream"` // ex: "mediawiki.recentchange"
+	Topic     string    `json:"topic"`
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+}
+
+// An event of StreamRecentChange
+type RecentChange struct {
+	Meta       Meta   `json:"meta"`
+	ID         int64  `json:"id"` // rcid
+	Type       string `json:"type"`
+	Namespace  int    `json:"namespace"`
+	Title      string `json:"title"`
+	TitleURL   string `json:"title_url"`
+	Comment    string `json:"comment"`
+	Timestamp  int64  `json:"timestamp"` // Unix time
+	User       string `json:"user"`
+	Bot        bool   `json:"bot"`
+	Minor      bool   `json:"minor"`
+	Patrolled  bool   `json:"patrolled"`
+	ServerURL  string `json:"server_url"`
+	ServerName string `json:"server_name"`
+	Wiki       string `json:"wiki"` // ex: "enwiki"
+	Length     struct {
+		Old int `json:"old"`
+		New int `json:"new"`
+	} `json:"length"`
+	Revision struct {
+		Old int64 `json:"old"`
+		New int64 `json:"new"`
+	} `json:"revision"`
+	LogID     int64  `json:"log_id"`
+	LogType   string `json:"log_type"`
+	LogAction string `json:"log_action"`
+}
+
+// An event of StreamPageCreate or StreamRevisionCreate
+type RevisionEvent struct {
+	Meta              Meta      `json:"meta"`
+	Database          string    `json:"database"` // ex: "enwiki"
+	PageID            int64     `json:"page_id"`
+	PageTitle         string    `json:"page_title"`
+	PageNamespace     int       `json:"page_namespace"`
+	PageIsRedirect    bool      `json:"page_is_redirect"`
+	RevID             int64     `json:"rev_id"`
+	RevParentID       int64     `json:"rev_parent_id"`
+	RevTimestamp      time.Time `json:"rev_timestamp"`
+	RevSHA1           string    `json:"rev_sha1"`
+	RevLen            int       `json:"rev_len"`
+	RevMinorEdit      bool      `json:"rev_minor_edit"`
+	RevContentModel   string    `json:"rev_content_model"`
+	RevContentChanged bool      `json:"rev_content_changed"`
+	Comment           string    `json:"comment"`
+	Performer         Performer `json:"performer"`
+}
+
+// The user of a RevisionEvent
+type Performer struct {
+	UserText   string   `json:"user_text"`
+	UserID     int64    `json:"user_id"`
+	UserIsBot  bool     `json:"user_is_bot"`
+	UserGroups []string `json:"user_groups"`
+}
+
+// Time of the change as a time.Time
+func (change RecentChange) Time() time.Time {
+	return time.Unix(change.Timestamp, 0).UTC()
+}
+
+// Decode the data of an SSE message into an event
+func decodeEvent(id string, data []byte) (Event, error) {
+	event := Event{ID: id, Data: json.RawMessage(data)}
+	head := struct {
+		Meta Meta `json:"meta"`
+	}{}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return event, err
+	}
+	event.Meta = head.Meta
+	event.Stream = strings.TrimPrefix(head.Meta.Stream, "mediawiki.")
+	switch event.Stream {
+	case StreamRecentChange:
+		event.RecentChange = &RecentChange{}
+		if err := json.Unmarshal(data, event.RecentChange); err != nil {
+			return event, err
+		}
+	case StreamPageCreate, StreamRevisionCreate:
+		event.Revision = &RevisionEvent{}
+		if err := json.Unmarshal(data, event.Revision); err != nil {
+			return event, err
+		}
+	}
+	return event, nil
+}
+
+/*
+Wiki of the event, ex: "enwiki"
+*/
+func (event Event) Wiki() string {
+	if event.RecentChange != nil {
+		return event.RecentChange.Wiki
+	}
+	if event.Revision != nil {
+		return event.Revision.Database
+	}
+	return ""
+}
+
+/*
+Namespace of the page of the event, and whether the event has one
+*/
+func (event Event) Namespace() (int, bool) {
+	if event.RecentChange != nil {
+		return event.RecentChange.Namespace, true
+	}
+	if event.Revision != nil {
+		return event.Revision.PageNamespace, true
+	}
+	return 0, false
+}
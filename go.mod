@@ -3,7 +3,8 @@ module github.com/trietmn/go-wiki
 go 1.18
 
 require (
-	github.com/anaskhan96/soup v1.2.5 // indirect
-	golang.org/x/net v0.0.0-20200114155413-6afb5195e5aa // indirect
-	golang.org/x/text v0.3.0 // indirect
+	github.com/anaskhan96/soup v1.2.5
+	golang.org/x/net v0.0.0-20200114155413-6afb5195e5aa
 )
+
+require golang.org/x/text v0.3.0 // indirect
@@ -1,9 +1,16 @@
 package models
 
 type RequestError struct {
-	Code  string `json:"code"`
-	Info  string `json:"info"`
-	Aster string `json:"*"`
+	Code        string           `json:"code"`
+	Info        string           `json:"info"`
+	Aster       string           `json:"*"`
+	AbuseFilter InnerAbuseFilter `json:"abusefilter"`
+}
+
+type InnerAbuseFilter struct {
+	ID          interface{} `json:"id"` // Number for the local filters, string for the global ones
+	Description string      `json:"description"`
+	Actions     []string    `json:"actions"`
 }
 
 type InnerBasic struct {
@@ -41,14 +48,67 @@ type InnerPage struct {
 	EditURL             string                   `json:"editurl"`
 	CanonicalURL        string                   `json:"canonicalurl"`
 	PageProps           map[string]string        `json:"pageprops"`
+	Protection          []InnerProtection        `json:"protection"`
+	TalkID              int                      `json:"talkid"`
+	Watchers            int                      `json:"watchers"`
+	VisitingWatchers    int                      `json:"visitingwatchers"`
+	DisplayTitle        string                   `json:"displaytitle"`
+	New                 *string                  `json:"new"`
+	LangLinks           []InnerLangLink          `json:"langlinks"`
+	CategoryInfo        InnerCategoryInfo        `json:"categoryinfo"`
+	Templates           []InnerPageTitle         `json:"templates"`
+	Description         string                   `json:"description"`
+	DescriptionSource   string                   `json:"descriptionsource"`
 	Missing             string                   `json:"missing"`
 	Extract             string                   `json:"extract"`
 	Revision            []map[string]interface{} `json:"revisions"`
 	Extlink             []map[string]string      `json:"extlinks"`
 	Link                []map[string]interface{} `json:"links"`
 	Category            []map[string]interface{} `json:"categories"`
-	ImageInfo           []map[string]string      `json:"imageinfo"`
+	ImageInfo           []InnerImageInfo         `json:"imageinfo"`
 	Coordinate          []map[string]interface{} `json:"coordinates"`
+	PageImage           string                   `json:"pageimage"`
+	Thumbnail           InnerThumbnail           `json:"thumbnail"`
+	Original            InnerThumbnail           `json:"original"`
+}
+
+type InnerImageInfo struct {
+	URL            string                      `json:"url"`
+	DescriptionURL string                      `json:"descriptionurl"`
+	ThumbURL       string                      `json:"thumburl"`
+	ThumbWidth     int                         `json:"thumbwidth"`
+	ThumbHeight    int                         `json:"thumbheight"`
+	Width          int                         `json:"width"`
+	Height         int                         `json:"height"`
+	Size           int                         `json:"size"`
+	Mime           string                      `json:"mime"`
+	SHA1           string                      `json:"sha1"`
+	ExtMetadata    map[string]InnerExtMetadata `json:"extmetadata"`
+}
+
+type InnerExtMetadata struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+type InnerLangLink struct {
+	Lang     string `json:"lang"`
+	Title    string `json:"*"`
+	URL      string `json:"url"`
+	LangName string `json:"langname"`
+	Autonym  string `json:"autonym"`
+}
+
+type InnerProtection struct {
+	Type   string `json:"type"`
+	Level  string `json:"level"`
+	Expiry string `json:"expiry"`
+}
+
+type InnerThumbnail struct {
+	Source string `json:"source"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
 }
 
 type InnerGeoSearch struct {
@@ -70,6 +130,183 @@ type InnerBacklinks struct {
 	Title string `json:"title"`
 }
 
+type InnerPageTitle struct {
+	PageID int    `json:"pageid"`
+	Ns     int    `json:"ns"`
+	Title  string `json:"title"`
+}
+
+type InnerCategoryInfo struct {
+	Size    int     `json:"size"`
+	Pages   int     `json:"pages"`
+	Files   int     `json:"files"`
+	Subcats int     `json:"subcats"`
+	Hidden  *string `json:"hidden"`
+}
+
+type InnerCategoryMember struct {
+	PageID        int    `json:"pageid"`
+	Ns            int    `json:"ns"`
+	Title         string `json:"title"`
+	Type          string `json:"type"`
+	SortKey       string `json:"sortkey"`
+	SortKeyPrefix string `json:"sortkeyprefix"`
+	Timestamp     string `json:"timestamp"`
+}
+
+type InnerRecentChange struct {
+	Type      string   `json:"type"`
+	Ns        int      `json:"ns"`
+	Title     string   `json:"title"`
+	PageID    int      `json:"pageid"`
+	RevID     int      `json:"revid"`
+	OldRevID  int      `json:"old_revid"`
+	RCID      int      `json:"rcid"`
+	User      string   `json:"user"`
+	Anon      *string  `json:"anon"`
+	Bot       *string  `json:"bot"`
+	Minor     *string  `json:"minor"`
+	New       *string  `json:"new"`
+	OldLen    int      `json:"oldlen"`
+	NewLen    int      `json:"newlen"`
+	Timestamp string   `json:"timestamp"`
+	Comment   string   `json:"comment"`
+	Tags      []string `json:"tags"`
+	LogType   string   `json:"logtype"`
+	LogAction string   `json:"logaction"`
+}
+
+type InnerCompare struct {
+	FromRevID int    `json:"fromrevid"`
+	ToRevID   int    `json:"torevid"`
+	Body      string `json:"*"`
+}
+
+type InnerEdit struct {
+	Result       string                 `json:"result"`
+	PageID       int                    `json:"pageid"`
+	Title        string                 `json:"title"`
+	ContentModel string                 `json:"contentmodel"`
+	OldRevID     int                    `json:"oldrevid"`
+	NewRevID     int                    `json:"newrevid"`
+	NewTimestamp string                 `json:"newtimestamp"`
+	New          *string                `json:"new"`
+	NoChange     *string                `json:"nochange"`
+	Code         string                 `json:"code"` // Failures of the extensions, ex: abuse filter on old wikis
+	Info         string                 `json:"info"`
+	Captcha      map[string]interface{} `json:"captcha"`
+}
+
+type InnerUpload struct {
+	Result    string                 `json:"result"`
+	Filename  string                 `json:"filename"`
+	FileKey   string                 `json:"filekey"`
+	Offset    int64                  `json:"offset"`
+	Warnings  map[string]interface{} `json:"warnings"`
+	ImageInfo InnerImageInfo         `json:"imageinfo"`
+}
+
+type InnerMovedPage struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type InnerMove struct {
+	From            string           `json:"from"`
+	To              string           `json:"to"`
+	Reason          string           `json:"reason"`
+	RedirectCreated *string          `json:"redirectcreated"`
+	TalkFrom        string           `json:"talkfrom"`
+	TalkTo          string           `json:"talkto"`
+	Subpages        []InnerMovedPage `json:"subpages"`
+	TalkSubpages    []InnerMovedPage `json:"subpages-talk"`
+}
+
+type InnerDelete struct {
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+	LogID  int    `json:"logid"`
+}
+
+type InnerUndelete struct {
+	Title        string `json:"title"`
+	Revisions    int    `json:"revisions"`
+	FileVersions int    `json:"fileversions"`
+	Reason       string `json:"reason"`
+}
+
+type InnerProtect struct {
+	Title       string              `json:"title"`
+	Reason      string              `json:"reason"`
+	Cascade     *string             `json:"cascade"`
+	Protections []map[string]string `json:"protections"`
+}
+
+type InnerPurge struct {
+	Ns         int     `json:"ns"`
+	Title      string  `json:"title"`
+	Purged     *string `json:"purged"`
+	LinkUpdate *string `json:"linkupdate"`
+	Missing    *string `json:"missing"`
+	Invalid    *string `json:"invalid"`
+}
+
+type InnerUser struct {
+	UserID           int      `json:"userid"`
+	Name             string   `json:"name"`
+	EditCount        int      `json:"editcount"`
+	Registration     string   `json:"registration"`
+	Groups           []string `json:"groups"`
+	Gender           string   `json:"gender"`
+	BlockID          int      `json:"blockid"`
+	BlockedBy        string   `json:"blockedby"`
+	BlockReason      string   `json:"blockreason"`
+	BlockedTimestamp string   `json:"blockedtimestamp"`
+	BlockExpiry      string   `json:"blockexpiry"`
+	BlockPartial     *string  `json:"blockpartial"`
+	Missing          *string  `json:"missing"`
+	Invalid          *string  `json:"invalid"`
+}
+
+type InnerUserContrib struct {
+	UserID    int      `json:"userid"`
+	User      string   `json:"user"`
+	PageID    int      `json:"pageid"`
+	RevID     int      `json:"revid"`
+	ParentID  int      `json:"parentid"`
+	Ns        int      `json:"ns"`
+	Title     string   `json:"title"`
+	Timestamp string   `json:"timestamp"`
+	Comment   string   `json:"comment"`
+	Size      int      `json:"size"`
+	SizeDiff  int      `json:"sizediff"`
+	New       *string  `json:"new"`
+	Minor     *string  `json:"minor"`
+	Top       *string  `json:"top"`
+	Tags      []string `json:"tags"`
+}
+
+type InnerLogin struct {
+	Result   string `json:"result"`
+	Reason   string `json:"reason"`
+	UserID   int    `json:"lguserid"`
+	Username string `json:"lgusername"`
+}
+
+type InnerClientLogin struct {
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	MessageCode string `json:"messagecode"`
+	Username    string `json:"username"`
+}
+
+type InnerUserInfo struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Groups []string `json:"groups"`
+	Rights []string `json:"rights"`
+}
+
 type RequestQuery struct {
 	SearchInfo InnerSearchInfo      `json:"searchinfo"`
 	Normalize  []InnerNormalize     `json:"normalized"`
@@ -80,6 +317,20 @@ type RequestQuery struct {
 	Random     []InnerSearch        `json:"random"`
 	Language   []map[string]string  `json:"languages"`
 	Backlinks  []InnerBacklinks     `json:"backlinks"`
+	// list=categorymembers
+	CategoryMembers []InnerCategoryMember `json:"categorymembers"`
+	// list=embeddedin
+	EmbeddedIn []InnerPageTitle `json:"embeddedin"`
+	// list=recentchanges
+	RecentChanges []InnerRecentChange `json:"recentchanges"`
+	// meta=tokens
+	Tokens map[string]string `json:"tokens"`
+	// list=users
+	Users []InnerUser `json:"users"`
+	// list=usercontribs
+	UserContribs []InnerUserContrib `json:"usercontribs"`
+	// meta=userinfo
+	UserInfo InnerUserInfo `json:"userinfo"`
 }
 
 /*
@@ -93,4 +344,14 @@ type RequestResult struct {
 	Servedby      string                 `json:"servedby"`
 	Continue      map[string]interface{} `json:"continue"`
 	Parse         map[string]interface{} `json:"parse"`
+	Compare       InnerCompare           `json:"compare"`
+	Login         InnerLogin             `json:"login"`
+	ClientLogin   InnerClientLogin       `json:"clientlogin"`
+	Edit          InnerEdit              `json:"edit"`
+	Upload        InnerUpload            `json:"upload"`
+	Move          InnerMove              `json:"move"`
+	Delete        InnerDelete            `json:"delete"`
+	Undelete      InnerUndelete          `json:"undelete"`
+	Protect       InnerProtect           `json:"protect"`
+	Purge         []InnerPurge           `json:"purge"`
 }
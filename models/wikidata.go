@@ -0,0 +1,54 @@
+package models
+
+import "encoding/json"
+
+/*
+The result of the wbgetentities action of a Wikibase API
+*/
+type WikidataResult struct {
+	Error    RequestError              `json:"error"`
+	Entities map[string]WikidataEntity `json:"entities"`
+	Success  int                       `json:"success"`
+}
+
+type WikidataEntity struct {
+	ID           string                         `json:"id"`
+	Type         string                         `json:"type"`
+	Missing      *string                        `json:"missing"`
+	Labels       map[string]WikidataText        `json:"labels"`
+	Descriptions map[string]WikidataText        `json:"descriptions"`
+	Aliases      map[string][]WikidataText      `json:"aliases"`
+	Claims       map[string][]WikidataStatement `json:"claims"`
+	Sitelinks    map[string]WikidataSitelink    `json:"sitelinks"`
+}
+
+type WikidataText struct {
+	Language string `json:"language"`
+	Value    string `json:"value"`
+}
+
+type WikidataSitelink struct {
+	Site   string   `json:"site"`
+	Title  string   `json:"title"`
+	URL    string   `json:"url"`
+	Badges []string `json:"badges"`
+}
+
+type WikidataStatement struct {
+	ID         string                    `json:"id"`
+	Rank       string                    `json:"rank"`
+	MainSnak   WikidataSnak              `json:"mainsnak"`
+	Qualifiers map[string][]WikidataSnak `json:"qualifiers"`
+}
+
+type WikidataSnak struct {
+	SnakType  string            `json:"snaktype"`
+	Property  string            `json:"property"`
+	DataType  string            `json:"datatype"`
+	DataValue WikidataDataValue `json:"datavalue"`
+}
+
+type WikidataDataValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
@@ -0,0 +1,271 @@
+package page
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/auth"
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Options of Move
+type MoveOptions struct {
+	MoveTalk       bool   // Also move the talk page
+	MoveSubpages   bool   // Also move the subpages, and the ones of the talk page with MoveTalk
+	NoRedirect     bool   // Do not leave a redirect at the old title. The user needs the suppressredirect right
+	IgnoreWarnings bool   // Move even if the API warns about it, ex: a shared file with the same name
+	Watchlist      string // Any of the Watchlist constants. Use "" for the default of the API
+}
+
+// A page moved to a new title
+type MovedPage struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Result of a successful move
+type MoveResult struct {
+	From            string      `json:"from"`
+	To              string      `json:"to"`
+	RedirectCreated bool        `json:"redirectcreated"`
+	Talk            *MovedPage  `json:"talk"`     // nil if the talk page has not been moved
+	Subpages        []MovedPage `json:"subpages"` // Moved subpages, with the ones of the talk page
+}
+
+// Result of a successful undelete
+type UndeleteResult struct {
+	Title        string `json:"title"`
+	Revisions    int    `json:"revisions"`    // Number of restored revisions
+	FileVersions int    `json:"fileversions"` // Number of restored file versions
+}
+
+/*
+Move the page to a new title with action=move, using the activated auth session. The page title is updated.
+
+Keyword arguments:
+
+* to: The new title
+
+* reason: Reason of the move
+
+* opts: Talk page, subpages and redirect options
+
+Return:
+
+* The moved pages
+
+* Error: *utils.PageExistsError if the new title is taken, *utils.ProtectedPageError, *utils.PermissionError...
+*/
+func (page *WikipediaPage) Move(to string, reason string, opts MoveOptions) (MoveResult, error) {
+	args := map[string]string{
+		"action": "move",
+		"from":   page.Title,
+		"to":     to,
+		"reason": reason,
+	}
+	if opts.MoveTalk {
+		args["movetalk"] = ""
+	}
+	if opts.MoveSubpages {
+		args["movesubpages"] = ""
+	}
+	if opts.NoRedirect {
+		args["noredirect"] = ""
+	}
+	if opts.IgnoreWarnings {
+		args["ignorewarnings"] = ""
+	}
+	if opts.Watchlist != "" {
+		args["watchlist"] = opts.Watchlist
+	}
+	res, err := page.post(args)
+	if err != nil {
+		return MoveResult{}, err
+	}
+	move := res.Move
+	result := MoveResult{
+		From:            move.From,
+		To:              move.To,
+		RedirectCreated: move.RedirectCreated != nil,
+		Subpages:        []MovedPage{},
+	}
+	if move.TalkTo != "" {
+		result.Talk = &MovedPage{From: move.TalkFrom, To: move.TalkTo}
+	}
+	for _, p := range append(move.Subpages, move.TalkSubpages...) {
+		// The subpages that could not be moved have an error instead of a title
+		if p.To != "" {
+			result.Subpages = append(result.Subpages, MovedPage{From: p.From, To: p.To})
+		}
+	}
+	utils.Cache.InvalidateTitle(page.Title)
+	utils.Cache.InvalidateTitle(result.To)
+	page.Title = result.To
+	return result, nil
+}
+
+/*
+Delete the page with action=delete, using the activated auth session.
+
+Keyword arguments:
+
+* reason: Reason of the deletion
+
+* deleteTalk: Also delete the talk page
+
+Return:
+
+* ID of the deletion in the log
+
+* Error: *utils.MissingPageError if the page does not exist, *utils.PermissionError...
+*/
+func (page *WikipediaPage) Delete(reason string, deleteTalk bool) (int, error) {
+	args := map[string]string{
+		"action": "delete",
+		"title":  page.Title,
+		"reason": reason,
+	}
+	if deleteTalk {
+		args["deletetalk"] = ""
+	}
+	res, err := page.post(args)
+	if err != nil {
+		return 0, err
+	}
+	utils.Cache.InvalidateTitle(page.Title)
+	return res.Delete.LogID, nil
+}
+
+/*
+Restore the deleted revisions of a page with action=undelete, using the activated auth session.
+
+Keyword arguments:
+
+* title: Title of the deleted page
+
+* reason: Reason of the restoration
+*/
+func Undelete(title string, reason string) (UndeleteResult, error) {
+	page := WikipediaPage{Title: title}
+	res, err := page.post(map[string]string{
+		"action": "undelete",
+		"title":  title,
+		"reason": reason,
+	})
+	if err != nil {
+		return UndeleteResult{}, err
+	}
+	utils.Cache.InvalidateTitle(title)
+	return UndeleteResult{
+		Title:        res.Undelete.Title,
+		Revisions:    res.Undelete.Revisions,
+		FileVersions: res.Undelete.FileVersions,
+	}, nil
+}
+
+/*
+Change the protections of the page with action=protect, using the activated auth session.
+The page.Protection field is replaced by the new protections.
+
+Keyword arguments:
+
+* protections: Level and expiry of each action, ex: {Type: "edit", Level: "sysop"}. A zero Expiry never expires,
+the "all" level removes the protection of the action
+
+* reason: Reason of the change
+
+* cascade: Also protect the pages transcluded in the page
+
+Return:
+
+* The protections applied by the API
+
+* Error
+*/
+func (page *WikipediaPage) Protect(protections []Protection, reason string, cascade bool) ([]Protection, error) {
+	if len(protections) == 0 {
+		return []Protection{}, errors.New("no protection to change")
+	}
+	levels := make([]string, len(protections))
+	expiries := make([]string, len(protections))
+	for i, p := range protections {
+		levels[i] = p.Type + "=" + p.Level
+		expiries[i] = "infinite"
+		if !p.Expiry.IsZero() {
+			expiries[i] = p.Expiry.UTC().Format(time.RFC3339)
+		}
+	}
+	args := map[string]string{
+		"action":      "protect",
+		"title":       page.Title,
+		"protections": strings.Join(levels, "|"),
+		"expiry":      strings.Join(expiries, "|"),
+		"reason":      reason,
+	}
+	if cascade {
+		args["cascade"] = ""
+	}
+	res, err := page.post(args)
+	if err != nil {
+		return []Protection{}, err
+	}
+	result := []Protection{}
+	for _, p := range res.Protect.Protections {
+		for action, level := range p {
+			if action != "expiry" {
+				result = append(result, Protection{Type: action, Level: level, Expiry: parseTimestamp(p["expiry"])})
+			}
+		}
+	}
+	utils.Cache.InvalidateTitle(page.Title)
+	page.Protection = make([]Protection, 0, len(result))
+	for _, p := range result {
+		if p.Level != "all" && p.Level != "" {
+			page.Protection = append(page.Protection, p)
+		}
+	}
+	return result, nil
+}
+
+/*
+Purge the cache of the page on the server with action=purge, and the cache of the library.
+Uses the activated auth session if any, the anonymous users can purge too.
+
+Keyword arguments:
+
+* forceLinkUpdate: Also update the links tables, ex: after a template change
+*/
+func (page *WikipediaPage) Purge(forceLinkUpdate bool) error {
+	args := page.languageArgs(map[string]string{
+		"action": "purge",
+		"titles": page.Title,
+	})
+	if forceLinkUpdate {
+		args["forcelinkupdate"] = ""
+	}
+	var res models.RequestResult
+	var err error
+	if auth.Default != nil {
+		res, err = auth.Default.Post(args)
+	} else {
+		res, err = utils.PostWikiApi(utils.HTTPClient, args)
+	}
+	if err != nil {
+		return err
+	}
+	if err := utils.ResultError(res); err != nil {
+		return err
+	}
+	utils.Cache.InvalidateTitle(page.Title)
+	for _, p := range res.Purge {
+		if p.Missing != nil {
+			return utils.NewAPIError("missingtitle", "the page does not exist")
+		}
+		if p.Invalid != nil {
+			return utils.NewAPIError("invalidtitle", "the title is invalid")
+		}
+	}
+	return nil
+}
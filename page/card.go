@@ -0,0 +1,82 @@
+package page
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Summary of a page for hover cards and previews
+type Card struct {
+	Title             string `json:"title"`
+	Description       string `json:"description"`       // Short description, ex: "English mathematician (1815–1852)"
+	DescriptionSource string `json:"descriptionsource"` // "local" (wikibase-shortdesc), "central" (Wikidata) or empty
+	Extract           string `json:"extract"`           // Plain text of the intro
+	URL               string `json:"url"`               // Canonical URL of the page
+	Image             string `json:"image"`             // File name of the lead image, without the "File:" prefix
+	ThumbURL          string `json:"thumburl"`
+	ThumbWidth        int    `json:"thumbwidth"`
+	ThumbHeight       int    `json:"thumbheight"`
+}
+
+/*
+Get the short description, the lead image thumbnail, the intro and the canonical URL of the page in a single request.
+
+Keyword arguments:
+
+* thumbSize: Max width and height of the thumbnail in pixels. Use 0 for the API default
+
+Return:
+
+* The card of the page
+
+* Error
+*/
+func (page *WikipediaPage) GetCard(thumbSize int) (Card, error) {
+	args := map[string]string{
+		"action":      "query",
+		"prop":        "description|pageimages|extracts|info|pageprops",
+		"piprop":      "thumbnail|name",
+		"exintro":     "",
+		"explaintext": "",
+		"inprop":      "url",
+		"ppprop":      "wikibase-shortdesc",
+		"titles":      page.Title,
+	}
+	if thumbSize > 0 {
+		args["pithumbsize"] = strconv.Itoa(thumbSize)
+	}
+	res, err := page.request(args)
+	if err != nil {
+		return Card{}, err
+	}
+	if res.Error.Code != "" {
+		return Card{}, errors.New(res.Error.Info)
+	}
+	p, ok := res.Query.Page[strconv.Itoa(page.PageID)]
+	if !ok {
+		return Card{}, errors.New("missing")
+	}
+	card := Card{
+		Title:             p.Title,
+		Description:       p.Description,
+		DescriptionSource: p.DescriptionSource,
+		Extract:           p.Extract,
+		URL:               p.CanonicalURL,
+		Image:             p.PageImage,
+		ThumbURL:          p.Thumbnail.Source,
+		ThumbWidth:        p.Thumbnail.Width,
+		ThumbHeight:       p.Thumbnail.Height,
+	}
+	// Without the ShortDescription extension, the description is only in the page props
+	if card.Description == "" && p.PageProps["wikibase-shortdesc"] != "" {
+		card.Description = p.PageProps["wikibase-shortdesc"]
+		card.DescriptionSource = "local"
+	}
+	if card.URL == "" {
+		card.URL = p.FullURL
+	}
+	if page.Summary == "" {
+		page.Summary = card.Extract
+	}
+	return card, nil
+}
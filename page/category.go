@@ -0,0 +1,158 @@
+package page
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Max number of titles in a single query
+const maxTitlesPerQuery = 50
+
+// Options of WikipediaPage.GetCategories
+type CategoryOptions struct {
+	ExcludeHidden bool // Drop the hidden maintenance categories, ex: "Articles with dead external links"
+	WithInfo      bool // Also get the number of pages, subcategories and files of each category
+}
+
+// A category of a page
+type Category struct {
+	Title         string        `json:"title"` // Title with the "Category:" prefix
+	Name          string        `json:"name"`  // Title without the "Category:" prefix
+	SortKey       string        `json:"sortkey"`
+	SortKeyPrefix string        `json:"sortkeyprefix"`
+	Timestamp     time.Time     `json:"timestamp"` // When the page was added to the category
+	Hidden        bool          `json:"hidden"`
+	Info          *CategoryInfo `json:"info"` // Only set with CategoryOptions.WithInfo
+}
+
+// Size of a category
+type CategoryInfo struct {
+	Title   string `json:"title"`
+	Size    int    `json:"size"` // Total number of members
+	Pages   int    `json:"pages"`
+	Subcats int    `json:"subcats"`
+	Files   int    `json:"files"`
+	Hidden  bool   `json:"hidden"`
+}
+
+/*
+Get the categories of the page with their sort key, timestamp and hidden flag
+
+Keyword arguments:
+
+* opts: Set ExcludeHidden to drop the hidden categories, WithInfo to get the size of each category
+
+Return:
+
+* List of categories
+
+* Error
+*/
+func (page *WikipediaPage) GetCategories(opts CategoryOptions) ([]Category, error) {
+	args := page.languageArgs(map[string]string{
+		"action":  "query",
+		"prop":    "categories",
+		"clprop":  "hidden|sortkey|timestamp",
+		"cllimit": "max",
+		"titles":  page.Title,
+	})
+	result := []Category{}
+	err := utils.ContinueQuery(args, func(res models.RequestResult) bool {
+		for _, p := range res.Query.Page {
+			for _, v := range p.Category {
+				c := makeCategory(v)
+				if opts.ExcludeHidden && c.Hidden {
+					continue
+				}
+				result = append(result, c)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return result, err
+	}
+	if opts.WithInfo && len(result) > 0 {
+		titles := make([]string, len(result))
+		for i, c := range result {
+			titles[i] = c.Title
+		}
+		infos, err := getCategoryInfo(titles, page.Language)
+		if err != nil {
+			return result, err
+		}
+		for i := range result {
+			if info, ok := infos[result[i].Title]; ok {
+				result[i].Info = &info
+			}
+		}
+	}
+	return result, nil
+}
+
+func makeCategory(v map[string]interface{}) Category {
+	c := Category{}
+	c.Title, _ = v["title"].(string)
+	c.Name = strings.TrimPrefix(c.Title, "Category:")
+	c.SortKey, _ = v["sortkey"].(string)
+	c.SortKeyPrefix, _ = v["sortkeyprefix"].(string)
+	if s, ok := v["timestamp"].(string); ok {
+		c.Timestamp = parseTimestamp(s)
+	}
+	_, c.Hidden = v["hidden"]
+	return c
+}
+
+/*
+Get the number of pages, subcategories and files of categories.
+The titles must have the "Category:" prefix. Missing categories are skipped.
+
+Return a map from the category titles to their info
+*/
+func GetCategoryInfo(titles []string) (map[string]CategoryInfo, error) {
+	return getCategoryInfo(titles, "")
+}
+
+func getCategoryInfo(titles []string, lang string) (map[string]CategoryInfo, error) {
+	result := map[string]CategoryInfo{}
+	for start := 0; start < len(titles); start += maxTitlesPerQuery {
+		end := start + maxTitlesPerQuery
+		if end > len(titles) {
+			end = len(titles)
+		}
+		args := map[string]string{
+			"action": "query",
+			"prop":   "categoryinfo",
+			"titles": strings.Join(titles[start:end], "|"),
+		}
+		if lang != "" {
+			args[utils.LangArg] = lang
+		}
+		res, err := utils.WikiRequester(args)
+		if err != nil {
+			return result, err
+		}
+		if res.Error.Code != "" {
+			return result, errors.New(res.Error.Info)
+		}
+		for id, p := range res.Query.Page {
+			if n, err := strconv.Atoi(id); err == nil && n < 0 && p.CategoryInfo.Size == 0 {
+				continue
+			}
+			result[p.Title] = CategoryInfo{
+				Title:   p.Title,
+				Size:    p.CategoryInfo.Size,
+				Pages:   p.CategoryInfo.Pages,
+				Subcats: p.CategoryInfo.Subcats,
+				Files:   p.CategoryInfo.Files,
+				Hidden:  p.CategoryInfo.Hidden != nil,
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,292 @@
+package page
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/trietmn/go-wiki/wikitext"
+)
+
+// A reference of the page, parsed from a <ref> tag or a citation template
+type Citation struct {
+	Name       string        `json:"name"`     // Name of the <ref>, used to reuse it
+	Group      string        `json:"group"`    // Group of the <ref>, ex: "note"
+	Template   string        `json:"template"` // Citation template, ex: "Cite web". Empty for free-form references
+	Title      string        `json:"title"`
+	Authors    []string      `json:"authors"`
+	Date       string        `json:"date"`
+	Publisher  string        `json:"publisher"`
+	Work       string        `json:"work"` // Website, newspaper, journal or magazine
+	URL        string        `json:"url"`
+	ArchiveURL string        `json:"archiveurl"`
+	DOI        string        `json:"doi"`
+	ISBN       string        `json:"isbn"`
+	AccessDate string        `json:"accessdate"`
+	Raw        string        `json:"raw"`  // Raw wikitext of the reference
+	Text       string        `json:"text"` // Plain text of the reference
+	Uses       []CitationUse `json:"uses"` // Places of the page citing the reference
+}
+
+// A place of the page citing a reference
+type CitationUse struct {
+	Section  string `json:"section"`  // Title of the section, empty for the lead
+	Sentence string `json:"sentence"` // Plain text of the sentence before the reference marker
+}
+
+// Position of a <ref> in the plain text of a block
+type refPosition struct {
+	tag    *wikitext.Tag
+	offset int
+}
+
+/*
+Get the references of the page with their structured fields. Save them into the page.Citations for later use
+*/
+func (page *WikipediaPage) GetCitations() ([]Citation, error) {
+	if page.Citations != nil {
+		return page.Citations, nil
+	}
+	text, err := page.GetWikitext()
+	if err != nil {
+		return []Citation{}, err
+	}
+	page.Citations = ParseCitations(text)
+	return page.Citations, nil
+}
+
+/*
+Extract the references of a wikitext: the content of the <ref> tags and the citation templates
+used outside of them (in a "Further reading" list for example).
+
+Named references are merged, every reuse adds an entry to Uses
+*/
+func ParseCitations(text string) []Citation {
+	res := []*Citation{}
+	named := map[string]*Citation{}
+	section := ""
+	add := func(tag *wikitext.Tag, use *CitationUse) {
+		name, _ := tag.Attr("name")
+		group, _ := tag.Attr("group")
+		key := group + "\x00" + name
+		c, ok := named[key]
+		if name == "" || !ok {
+			c = &Citation{Name: name, Group: group, Authors: []string{}, Uses: []CitationUse{}}
+			res = append(res, c)
+			if name != "" {
+				named[key] = c
+			}
+		}
+		if c.Raw == "" && !tag.SelfClosing && strings.TrimSpace(tag.Contents.String()) != "" {
+			fillCitation(c, tag.Contents)
+		}
+		if use != nil {
+			c.Uses = append(c.Uses, *use)
+		}
+	}
+	for _, block := range wikitext.Parse(text) {
+		if h, ok := block.(*wikitext.Heading); ok {
+			section = h.Text()
+			continue
+		}
+		var b strings.Builder
+		refs := []refPosition{}
+		flattenRefs(wikitext.Nodes{block}, &b, &refs)
+		plain := b.String()
+		for _, r := range refs {
+			use := &CitationUse{Section: section, Sentence: sentenceBefore(plain, r.offset)}
+			add(r.tag, use)
+		}
+		// Citation templates outside of <ref> tags
+		for _, t := range citationTemplatesOutsideRefs(wikitext.Nodes{block}) {
+			c := &Citation{Authors: []string{}, Uses: []CitationUse{{Section: section}}}
+			fillCitation(c, wikitext.Nodes{t})
+			res = append(res, c)
+		}
+		// List-defined references: <references><ref name="x">...</ref></references>
+		for _, list := range (wikitext.Nodes{block}).Tags("references") {
+			for _, n := range list.Contents.Refs() {
+				add(n, nil)
+			}
+		}
+	}
+	citations := make([]Citation, len(res))
+	for i, c := range res {
+		citations[i] = *c
+	}
+	return citations
+}
+
+/*
+Write the plain text of the nodes into b, recording the position of each <ref> tag.
+The refs of a <references> list are skipped, they do not cite anything
+*/
+func flattenRefs(nodes wikitext.Nodes, b *strings.Builder, refs *[]refPosition) {
+	for _, n := range nodes {
+		switch v := n.(type) {
+		case *wikitext.Tag:
+			switch strings.ToLower(v.Name) {
+			case "ref":
+				*refs = append(*refs, refPosition{tag: v, offset: b.Len()})
+			case "references":
+			default:
+				flattenRefs(v.Contents, b, refs)
+			}
+		case *wikitext.Paragraph:
+			flattenRefs(v.Contents, b, refs)
+		case *wikitext.List:
+			for _, item := range v.Items {
+				flattenRefs(item.Contents, b, refs)
+				b.WriteString("\n")
+			}
+		case *wikitext.Table:
+			for _, row := range v.Rows {
+				for _, cell := range row.Cells {
+					flattenRefs(cell.Contents, b, refs)
+					b.WriteString("\n")
+				}
+			}
+		case *wikitext.Wikilink:
+			if len(v.Label.Refs()) > 0 {
+				// Image captions can cite references
+				b.WriteString("\n")
+				flattenRefs(v.Label, b, refs)
+				b.WriteString("\n")
+			} else {
+				b.WriteString(v.Text())
+			}
+		case *wikitext.Template:
+			// The templates have no text, but their values can cite references, ex: in an infobox
+			for _, p := range v.Params {
+				if len(p.Value.Refs()) > 0 {
+					b.WriteString("\n")
+					flattenRefs(p.Value, b, refs)
+					b.WriteString("\n")
+				}
+			}
+		default:
+			b.WriteString(n.Text())
+		}
+	}
+}
+
+// Return the sentence ending at offset in a plain text
+func sentenceBefore(text string, offset int) string {
+	before := strings.TrimRight(text[:offset], " ")
+	start := 0
+	for i := len(before) - 2; i >= 0; i-- {
+		if before[i] == '\n' {
+			start = i + 1
+			break
+		}
+		if (before[i] == '.' || before[i] == '!' || before[i] == '?') && before[i+1] == ' ' {
+			start = i + 2
+			break
+		}
+	}
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(before[start:], " "))
+}
+
+// Return true if the template is a citation template like {{cite web}} or {{citation}}
+func isCitationTemplate(t *wikitext.Template) bool {
+	name := strings.ToLower(t.TemplateName())
+	return strings.HasPrefix(name, "cite ") || name == "citation" || name == "cite"
+}
+
+func citationTemplatesOutsideRefs(nodes wikitext.Nodes) []*wikitext.Template {
+	res := []*wikitext.Template{}
+	wikitext.Walk(nodes, func(n wikitext.Node) bool {
+		if tag, ok := n.(*wikitext.Tag); ok && (strings.EqualFold(tag.Name, "ref") || strings.EqualFold(tag.Name, "references")) {
+			return false
+		}
+		if t, ok := n.(*wikitext.Template); ok && isCitationTemplate(t) {
+			res = append(res, t)
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+/*
+Fill the fields of a citation from the content of a reference.
+The first citation template is used, free-form references only get a URL and a title from their first external link
+*/
+func fillCitation(c *Citation, contents wikitext.Nodes) {
+	c.Raw = strings.TrimSpace(contents.String())
+	c.Text = cleanText(contents.Text())
+	var cite *wikitext.Template
+	for _, t := range contents.Templates() {
+		if isCitationTemplate(t) {
+			cite = t
+			break
+		}
+	}
+	if cite == nil {
+		if links := contents.ExternalLinks(); len(links) > 0 {
+			c.URL = links[0].URL
+			c.Title = strings.TrimSpace(links[0].Label.Text())
+		}
+		return
+	}
+	param := func(names ...string) string {
+		for _, name := range names {
+			if p := cite.Get(name); p != nil {
+				if v := cleanWikitextValue(p.Value); v != "" {
+					return v
+				}
+			}
+		}
+		return ""
+	}
+	c.Template = cite.TemplateName()
+	c.Title = param("title", "script-title", "chapter")
+	c.Date = param("date", "year")
+	c.Publisher = param("publisher")
+	c.Work = param("website", "work", "newspaper", "journal", "magazine", "periodical")
+	c.URL = param("url", "chapter-url")
+	c.ArchiveURL = param("archive-url", "archiveurl")
+	c.DOI = param("doi")
+	c.ISBN = param("isbn", "ISBN")
+	c.AccessDate = param("access-date", "accessdate")
+	c.Authors = citationAuthors(param)
+	parts := []string{}
+	for _, part := range []string{strings.Join(c.Authors, "; "), c.Title, c.Work, c.Publisher, c.Date} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	c.Text = strings.Join(parts, ". ")
+}
+
+// Return the authors of a citation template from the author, last and first parameters
+func citationAuthors(param func(names ...string) string) []string {
+	res := []string{}
+Loop:
+	for i := 0; i <= 20; i++ {
+		n := ""
+		if i > 0 {
+			n = strconv.Itoa(i)
+		}
+		last := param("last"+n, "surname"+n, "author"+n+"-last", "author-last"+n)
+		first := param("first"+n, "given"+n, "author"+n+"-first", "author-first"+n)
+		author := param("author" + n)
+		switch {
+		case last != "" && first != "":
+			res = append(res, last+", "+first)
+		case last != "":
+			res = append(res, last)
+		case author != "":
+			res = append(res, author)
+		case i > 1:
+			break Loop
+		}
+	}
+	if len(res) == 0 {
+		for _, a := range strings.Split(param("authors", "vauthors"), ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				res = append(res, a)
+			}
+		}
+	}
+	return res
+}
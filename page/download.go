@@ -0,0 +1,305 @@
+package page
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/trietmn/go-wiki/utils"
+)
+
+const (
+	DefaultDownloadConcurrency = 4
+	DefaultManifestName        = "manifest.json"
+	partialSuffix              = ".part"
+)
+
+// Options of the media downloader
+type DownloadOptions struct {
+	Concurrency  int          // Max number of parallel downloads. Use 0 for DefaultDownloadConcurrency
+	ManifestName string       // Name of the manifest file written in the directory. Use "" for DefaultManifestName
+	Images       ImageOptions // Filters of the page images to download
+	Client       *http.Client // Client used for the downloads. Use nil for utils.HTTPClient without its total timeout
+}
+
+// The metadata of the files of a download directory
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+}
+
+// The metadata of a downloaded file
+type ManifestEntry struct {
+	File           string   `json:"file"` // Path of the file, relative to the directory
+	Title          string   `json:"title"`
+	URL            string   `json:"url"`
+	DescriptionURL string   `json:"descriptionurl"`
+	SourcePages    []string `json:"sourcepages"` // Titles of the pages using the file
+	License        string   `json:"license"`
+	LicenseURL     string   `json:"licenseurl"`
+	Artist         string   `json:"artist"`
+	Credit         string   `json:"credit"`
+	MIME           string   `json:"mime"`
+	Size           int64    `json:"size"`
+	SHA1           string   `json:"sha1"`
+	Error          string   `json:"error,omitempty"` // Error of the last download attempt
+}
+
+/*
+Download the images of the page into `dir`, then write the manifest mapping
+the files to their source page and license.
+
+Downloads use the library rate limiter and user-agent. Partial downloads are resumed
+and every file is checked against the SHA1 given by the API
+*/
+func (page *WikipediaPage) DownloadMedia(dir string, opts DownloadOptions) (Manifest, error) {
+	images, err := page.GetImages(opts.Images)
+	if err != nil {
+		return Manifest{}, err
+	}
+	return DownloadImages(images, page.Title, dir, opts)
+}
+
+/*
+Download images into `dir` and update its manifest. The manifest keeps the entries of the previous downloads,
+so multiple pages can share the same directory.
+
+Returns the manifest and an error describing the failed downloads, if any
+*/
+func DownloadImages(images []Image, source string, dir string, opts DownloadOptions) (Manifest, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultDownloadConcurrency
+	}
+	if opts.ManifestName == "" {
+		opts.ManifestName = DefaultManifestName
+	}
+	if opts.Client == nil {
+		// A large file takes longer than the timeout of the API requests
+		opts.Client = utils.TransferClient(utils.HTTPClient)
+	}
+	// The same file twice would be written by 2 goroutines at once
+	unique := make([]Image, 0, len(images))
+	seen := map[string]bool{}
+	for _, img := range images {
+		if name := MediaFileName(img.Title); !seen[name] {
+			seen[name] = true
+			unique = append(unique, img)
+		}
+	}
+	images = unique
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Manifest{}, err
+	}
+	manifestPath := filepath.Join(dir, opts.ManifestName)
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return manifest, err
+	}
+
+	entries := make([]ManifestEntry, len(images))
+	errs := make([]error, len(images))
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, img := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, img Image) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			entries[i] = makeManifestEntry(img, source)
+			errs[i] = downloadFile(opts.Client, img, filepath.Join(dir, entries[i].File))
+			if errs[i] != nil {
+				entries[i].Error = errs[i].Error()
+			}
+		}(i, img)
+	}
+	wg.Wait()
+
+	failed := 0
+	var first error
+	for i, entry := range entries {
+		manifest.add(entry)
+		if errs[i] != nil {
+			failed++
+			if first == nil {
+				first = fmt.Errorf("%v: %v", entry.Title, errs[i])
+			}
+		}
+	}
+	if err := manifest.Write(manifestPath); err != nil {
+		return manifest, err
+	}
+	if failed > 0 {
+		return manifest, fmt.Errorf("%v of %v downloads failed, first error: %v", failed, len(images), first)
+	}
+	return manifest, nil
+}
+
+/*
+Read a manifest file. Returns an empty manifest if the file does not exist
+*/
+func ReadManifest(path string) (Manifest, error) {
+	manifest := Manifest{Files: []ManifestEntry{}}
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(data, &manifest)
+	return manifest, err
+}
+
+/*
+Write the manifest as indented JSON
+*/
+func (manifest Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Add or replace the entry of a file, keeping the source pages of the previous entry
+func (manifest *Manifest) add(entry ManifestEntry) {
+	for i, old := range manifest.Files {
+		if old.File == entry.File {
+			for _, p := range old.SourcePages {
+				if !utils.Isin(entry.SourcePages, p) {
+					entry.SourcePages = append(entry.SourcePages, p)
+				}
+			}
+			manifest.Files[i] = entry
+			return
+		}
+	}
+	manifest.Files = append(manifest.Files, entry)
+}
+
+func makeManifestEntry(img Image, source string) ManifestEntry {
+	return ManifestEntry{
+		File:           MediaFileName(img.Title),
+		Title:          img.Title,
+		URL:            img.URL,
+		DescriptionURL: img.DescriptionURL,
+		SourcePages:    []string{source},
+		License:        img.License,
+		LicenseURL:     img.LicenseURL,
+		Artist:         img.Artist,
+		Credit:         img.Credit,
+		MIME:           img.MIME,
+		Size:           int64(img.Size),
+		SHA1:           img.SHA1,
+	}
+}
+
+/*
+Name of the local file of a media: the title without the "File:" prefix, with the spaces
+and path separators replaced by underscores
+*/
+func MediaFileName(title string) string {
+	if i := strings.Index(title, ":"); i >= 0 {
+		title = title[i+1:]
+	}
+	return strings.NewReplacer(" ", "_", "/", "_", "\\", "_").Replace(title)
+}
+
+// Return the hex SHA1 of a file
+func fileSHA1(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hasher := sha1.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+/*
+Download a file to path. An existing file with the right checksum is kept, a partial file is resumed
+with a Range request. The file only gets its final name once its checksum is verified
+*/
+func downloadFile(client *http.Client, img Image, path string) error {
+	if img.URL == "" {
+		return errors.New("the image has no URL")
+	}
+	if _, err := os.Stat(path); err == nil {
+		sum, err := fileSHA1(path)
+		if err == nil && (img.SHA1 == "" || sum == img.SHA1) {
+			return nil
+		}
+	}
+	partial := path + partialSuffix
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	request, err := utils.NewRequest("GET", img.URL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	utils.WaitRateLimit()
+	res, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case res.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is already complete
+		return finishDownload(img, partial, path)
+	case res.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+	default:
+		return fmt.Errorf("unable to download the file, status %v", res.StatusCode)
+	}
+	f, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, res.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return finishDownload(img, partial, path)
+}
+
+// Verify the checksum of a downloaded file and give it its final name
+func finishDownload(img Image, partial string, path string) error {
+	if img.SHA1 != "" {
+		sum, err := fileSHA1(partial)
+		if err != nil {
+			return err
+		}
+		if sum != img.SHA1 {
+			os.Remove(partial)
+			return fmt.Errorf("checksum mismatch, got %v, expect %v", sum, img.SHA1)
+		}
+	}
+	return os.Rename(partial, path)
+}
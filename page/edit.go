@@ -0,0 +1,292 @@
+package page
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/auth"
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Values of EditOptions.Watchlist
+const (
+	WatchlistWatch       = "watch"
+	WatchlistUnwatch     = "unwatch"
+	WatchlistPreferences = "preferences"
+	WatchlistNoChange    = "nochange"
+)
+
+// Options of the edit methods
+type EditOptions struct {
+	Minor     bool     // Mark the edit as minor
+	Bot       bool     // Mark the edit as a bot edit. The user needs the bot right
+	Watchlist string   // Any of the Watchlist constants. Use "" for the default of the API
+	Tags      []string // Change tags of the edit
+	// Timestamp of the revision the new text is based on. Zero for page.WikitextTimestamp
+	BaseTimestamp time.Time
+	// Time when the editing started. Zero for page.WikitextLoadedAt
+	StartTimestamp time.Time
+}
+
+// Result of a successful edit
+type EditResult struct {
+	PageID       int       `json:"pageid"`
+	Title        string    `json:"title"`
+	OldRevID     int       `json:"oldrevid"`
+	NewRevID     int       `json:"newrevid"` // Same as OldRevID when nothing has changed
+	NewTimestamp time.Time `json:"newtimestamp"`
+	New          bool      `json:"new"`      // The page has been created
+	NoChange     bool      `json:"nochange"` // The text was the same, no revision is saved
+}
+
+/*
+Replace the text of the page with action=edit, using the activated auth session.
+
+To detect the edit conflicts, load the text with GetWikitext before changing it: its revision is sent as the base of the edit,
+and the edit fails with a *utils.EditConflictError if the page has changed since. If the text is not loaded,
+the latest revision is loaded as the base, so an edit never overwrites a revision it knows nothing about.
+
+Keyword arguments:
+
+* text: The new wikitext of the page
+
+* summary: Edit summary
+
+* opts: Flags of the edit
+
+Return:
+
+* The result of the edit, with the new revision ID
+
+* Error: *utils.EditConflictError, *utils.ProtectedPageError, *utils.AbuseFilterError... see utils.NewAPIError
+*/
+func (page *WikipediaPage) Edit(text string, summary string, opts EditOptions) (EditResult, error) {
+	args := map[string]string{
+		"title":    page.Title,
+		"text":     text,
+		"summary":  summary,
+		"nocreate": "",
+	}
+	if opts.BaseTimestamp.IsZero() && page.WikitextTimestamp.IsZero() {
+		if auth.Default == nil {
+			return EditResult{}, auth.ErrNoSession
+		}
+		// A text set without its revision is not a base either
+		page.Wikitext = ""
+		if _, err := page.GetWikitext(); err != nil {
+			return EditResult{}, err
+		}
+	}
+	if page.WikitextRevID != 0 {
+		args["baserevid"] = strconv.Itoa(page.WikitextRevID)
+	}
+	if opts.BaseTimestamp.IsZero() {
+		opts.BaseTimestamp = page.WikitextTimestamp
+	}
+	if opts.StartTimestamp.IsZero() {
+		opts.StartTimestamp = page.WikitextLoadedAt
+	}
+	res, err := page.edit(args, opts)
+	if err != nil {
+		return res, err
+	}
+	if !res.NoChange {
+		page.resetContent()
+		page.WikitextTimestamp = res.NewTimestamp
+	}
+	// The new text is the base of the next edit
+	page.Wikitext = text
+	page.WikitextRevID = res.NewRevID
+	page.WikitextLoadedAt = time.Now().UTC()
+	return res, nil
+}
+
+/*
+Add a new section at the end of the page.
+
+Keyword arguments:
+
+* title: Title of the new section
+
+* text: Wikitext of the section, without its heading
+
+* summary: Edit summary. Use "" for the default summary, made from the section title
+
+* opts: Flags of the edit
+*/
+func (page *WikipediaPage) AppendSection(title string, text string, summary string, opts EditOptions) (EditResult, error) {
+	args := map[string]string{
+		"title":        page.Title,
+		"section":      "new",
+		"sectiontitle": title,
+		"text":         text,
+		"nocreate":     "",
+	}
+	if summary != "" {
+		args["summary"] = summary
+	}
+	res, err := page.edit(args, opts)
+	if err != nil {
+		return res, err
+	}
+	page.resetContent()
+	return res, nil
+}
+
+/*
+Add text at the beginning of the page, ex: a maintenance template.
+
+Keyword arguments:
+
+* text: Wikitext to add, with its line break
+
+* summary: Edit summary
+
+* opts: Flags of the edit
+*/
+func (page *WikipediaPage) PrependText(text string, summary string, opts EditOptions) (EditResult, error) {
+	args := map[string]string{
+		"title":       page.Title,
+		"prependtext": text,
+		"summary":     summary,
+		"nocreate":    "",
+	}
+	res, err := page.edit(args, opts)
+	if err != nil {
+		return res, err
+	}
+	page.resetContent()
+	return res, nil
+}
+
+/*
+Create a new page with action=edit, using the activated auth session.
+
+Returns a *utils.PageExistsError if the page already exists
+
+Keyword arguments:
+
+* title: Title of the page
+
+* text: Wikitext of the page
+
+* summary: Edit summary
+
+* opts: Flags of the edit
+*/
+func CreatePage(title string, text string, summary string, opts EditOptions) (EditResult, error) {
+	page := WikipediaPage{Title: title}
+	args := map[string]string{
+		"title":      title,
+		"text":       text,
+		"summary":    summary,
+		"createonly": "",
+	}
+	return page.edit(args, opts)
+}
+
+// Send an edit with the flags of the options and parse its result
+func (page *WikipediaPage) edit(args map[string]string, opts EditOptions) (EditResult, error) {
+	args["action"] = "edit"
+	if opts.Minor {
+		args["minor"] = ""
+	} else {
+		args["notminor"] = ""
+	}
+	if opts.Bot {
+		args["bot"] = ""
+	}
+	if opts.Watchlist != "" {
+		args["watchlist"] = opts.Watchlist
+	}
+	if len(opts.Tags) > 0 {
+		args["tags"] = strings.Join(opts.Tags, "|")
+	}
+	if !opts.BaseTimestamp.IsZero() {
+		args["basetimestamp"] = opts.BaseTimestamp.UTC().Format(time.RFC3339)
+	}
+	if !opts.StartTimestamp.IsZero() {
+		args["starttimestamp"] = opts.StartTimestamp.UTC().Format(time.RFC3339)
+	}
+	res, err := page.post(args)
+	if err != nil {
+		return EditResult{}, err
+	}
+	if res.Edit.Result != "Success" {
+		return EditResult{}, editFailure(res.Edit)
+	}
+	// The cached requests about the page are outdated
+	utils.Cache.InvalidateTitle(page.Title)
+	result := EditResult{
+		PageID:       res.Edit.PageID,
+		Title:        res.Edit.Title,
+		OldRevID:     res.Edit.OldRevID,
+		NewRevID:     res.Edit.NewRevID,
+		NewTimestamp: parseTimestamp(res.Edit.NewTimestamp),
+		New:          res.Edit.New != nil,
+		NoChange:     res.Edit.NoChange != nil,
+	}
+	if result.NoChange {
+		result.NewRevID = result.OldRevID
+	}
+	if result.PageID != 0 {
+		page.PageID = result.PageID
+	}
+	if result.NewRevID != 0 {
+		page.LastRevID = result.NewRevID
+	}
+	return result, nil
+}
+
+/*
+Send a write request about the page with the CSRF token of the activated auth session.
+Returns the typed error of the API error, if any
+*/
+func (page *WikipediaPage) post(args map[string]string) (models.RequestResult, error) {
+	session := auth.Default
+	if session == nil {
+		return models.RequestResult{}, auth.ErrNoSession
+	}
+	res, err := session.PostWithToken(auth.TokenCSRF, page.languageArgs(args))
+	if err != nil {
+		return models.RequestResult{}, err
+	}
+	if err := utils.ResultError(res); err != nil {
+		return models.RequestResult{}, err
+	}
+	return res, nil
+}
+
+// Error of an edit refused by an extension, ex: a captcha or an abuse filter on the old wikis
+func editFailure(edit models.InnerEdit) error {
+	if edit.Code != "" {
+		return utils.NewAPIError(edit.Code, edit.Info)
+	}
+	if edit.Captcha != nil {
+		return &utils.APIError{Code: "captcha", Info: "the edit needs a captcha"}
+	}
+	if edit.Result == "" {
+		return errors.New("unable to read the result of the edit")
+	}
+	return &utils.APIError{Code: strings.ToLower(edit.Result), Info: "the edit has failed: " + edit.Result}
+}
+
+// Forget the text, and everything made from it, after the page has changed
+func (page *WikipediaPage) resetContent() {
+	page.Content = ""
+	page.HTML = ""
+	page.Summary = ""
+	page.Wikitext = ""
+	page.WikitextRevID = 0
+	page.WikitextTimestamp = time.Time{}
+	page.WikitextLoadedAt = time.Time{}
+	page.CheckedInfobox = false
+	page.Infobox = nil
+	page.Citations = nil
+	page.Section = nil
+	page.SectionTree = nil
+	page.SectionOffset = nil
+}
@@ -0,0 +1,122 @@
+package page
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/trietmn/go-wiki/utils"
+	"golang.org/x/net/html"
+)
+
+var (
+	spaceRegex    = regexp.MustCompile(`[ \t\r\f\v\x{00a0}]+`)
+	footnoteRegex = regexp.MustCompile(`\[(?:\d+|[a-z]|note \d+|nb \d+|citation needed)\]`)
+	blockTags     = []string{"p", "div", "li", "ul", "ol", "dl", "dt", "dd", "table", "tr", "blockquote", "h1", "h2", "h3", "h4", "h5", "h6"}
+)
+
+// Return the value of the attribute `key` of the node
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// Return true if the node has the class `class`
+func hasClass(n *html.Node, class string) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, c := range strings.Fields(getAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if the node is a footnote marker, an edit link or some other invisible element
+func isNoise(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return n.Type == html.CommentNode
+	}
+	switch n.Data {
+	case "style", "script":
+		return true
+	case "sup":
+		return hasClass(n, "reference") || hasClass(n, "noprint")
+	}
+	if hasClass(n, "mw-editsection") || hasClass(n, "noprint") || hasClass(n, "sortkey") {
+		return true
+	}
+	return strings.Contains(strings.ReplaceAll(getAttr(n, "style"), " ", ""), "display:none")
+}
+
+/*
+Visible text of an HTML node without footnote markers, edit links and hidden elements.
+Line breaks and list items become new lines
+*/
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if isNoise(n) {
+			return
+		}
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			b.WriteString("\n")
+		case n.Type == html.ElementNode && utils.Isin(blockTags, n.Data):
+			b.WriteString("\n")
+		case n.Type == html.ElementNode && (n.Data == "td" || n.Data == "th"):
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(n)
+	return cleanText(b.String())
+}
+
+/*
+Remove footnote markers like [1] and [citation needed] from a text,
+collapse the spaces and trim every line. Empty lines are dropped
+*/
+func cleanText(s string) string {
+	s = footnoteRegex.ReplaceAllString(s, "")
+	lines := strings.Split(s, "\n")
+	res := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(spaceRegex.ReplaceAllString(line, " "))
+		if line != "" {
+			res = append(res, line)
+		}
+	}
+	return strings.Join(res, "\n")
+}
+
+// Render a node back to HTML
+func renderHTML(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&b, c)
+	}
+	return b.String()
+}
+
+// Return the title of the page an <a> element links to, or "" if it is not an internal link
+func linkTitle(n *html.Node) string {
+	href := getAttr(n, "href")
+	if !strings.HasPrefix(href, "/wiki/") || hasClass(n, "new") {
+		return ""
+	}
+	if title := getAttr(n, "title"); title != "" {
+		return title
+	}
+	return strings.ReplaceAll(strings.TrimPrefix(href, "/wiki/"), "_", " ")
+}
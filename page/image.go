@@ -0,0 +1,234 @@
+package page
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+const (
+	// Images smaller than this on both sides are considered as icons
+	DefaultIconSize = 100
+	// The extmetadata fields requested for every image
+	imageMetadataFilter = "LicenseShortName|LicenseUrl|Artist|Credit|ImageDescription"
+)
+
+// Words in the titles of the images used as icons (flags, UI sprites...). Whole words only, so "Silicon Valley.jpg" is not an icon
+var iconTitleRegex = regexp.MustCompile(`\b(?:flags? of|icons?|symbols?|sprites?|arrows?|pictograms?|wiktionary|commons-logo|question book|edit-clear|padlocks?)\b`)
+
+// An image of a page with its metadata
+type Image struct {
+	Title          string `json:"title"` // File title, ex: "File:Ada_Lovelace.jpg"
+	URL            string `json:"url"`
+	DescriptionURL string `json:"descriptionurl"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	Size           int    `json:"size"` // Size in bytes
+	MIME           string `json:"mime"`
+	SHA1           string `json:"sha1"`
+	ThumbURL       string `json:"thumburl"`
+	ThumbWidth     int    `json:"thumbwidth"`
+	ThumbHeight    int    `json:"thumbheight"`
+	License        string `json:"license"`
+	LicenseURL     string `json:"licenseurl"`
+	Artist         string `json:"artist"`
+	Credit         string `json:"credit"`
+	Description    string `json:"description"`
+}
+
+// Options of GetImages
+type ImageOptions struct {
+	ThumbWidth   int  // Width of the thumbnails to get. Use 0 to not get any thumbnail
+	ExcludeIcons bool // Exclude the images smaller than MinSize and the ones named like icons and flags
+	ExcludeSVG   bool // Exclude the SVG images, mostly logos and diagrams
+	MinSize      int  // Min width or height of an image when ExcludeIcons is set. Use 0 for DefaultIconSize
+}
+
+/*
+Get the images of the page with their size, MIME type, SHA1, license, artist and credit, sorted by title.
+
+Keyword arguments:
+
+* opts: Size of the thumbnails and filters
+*/
+func (page *WikipediaPage) GetImages(opts ImageOptions) ([]Image, error) {
+	args := imageInfoArgs(opts.ThumbWidth)
+	args["generator"] = "images"
+	args["gimlimit"] = "max"
+	res, err := page.ContinuedQuery(args)
+	if err != nil {
+		return []Image{}, err
+	}
+	images := make([]Image, 0, len(res))
+	for _, v := range res {
+		p := v.(models.InnerPage)
+		if len(p.ImageInfo) > 0 {
+			images = append(images, makeImage(p.Title, p.ImageInfo[0]))
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Title < images[j].Title })
+	return FilterImages(images, opts), nil
+}
+
+/*
+Get the lead image of the page, as chosen by the PageImages extension.
+
+Returns an error if the page has no lead image
+*/
+func (page *WikipediaPage) GetLeadImage(thumbWidth int) (Image, error) {
+	args := map[string]string{
+		"action": "query",
+		"prop":   "pageimages",
+		"piprop": "name",
+		"titles": page.Title,
+	}
+	res, err := page.request(args)
+	if err != nil {
+		return Image{}, err
+	}
+	if res.Error.Code != "" {
+		return Image{}, errors.New(res.Error.Info)
+	}
+	name := res.Query.Page[strconv.Itoa(page.PageID)].PageImage
+	if name == "" {
+		return Image{}, errors.New("the page has no lead image")
+	}
+	// Local files are only found on the wiki of the page
+	images, err := GetImageInfoInLanguage([]string{"File:" + name}, thumbWidth, page.Language)
+	if err != nil {
+		return Image{}, err
+	}
+	if len(images) == 0 {
+		return Image{}, errors.New("the page has no lead image")
+	}
+	return images[0], nil
+}
+
+/*
+Get the metadata of files by title, ex: "File:Ada_Lovelace.jpg". Missing files are skipped
+*/
+func GetImageInfo(titles []string, thumbWidth int) ([]Image, error) {
+	return GetImageInfoInLanguage(titles, thumbWidth, "")
+}
+
+/*
+Same as GetImageInfo, on the wiki of the language `lang`. Use "" for utils.WikiLanguage
+*/
+func GetImageInfoInLanguage(titles []string, thumbWidth int, lang string) ([]Image, error) {
+	images := []Image{}
+	for start := 0; start < len(titles); start += maxTitlesPerQuery {
+		end := start + maxTitlesPerQuery
+		if end > len(titles) {
+			end = len(titles)
+		}
+		args := imageInfoArgs(thumbWidth)
+		args["titles"] = strings.Join(titles[start:end], "|")
+		if lang != "" {
+			args[utils.LangArg] = lang
+		}
+		res, err := utils.WikiRequester(args)
+		if err != nil {
+			return images, err
+		}
+		if res.Error.Code != "" {
+			return images, errors.New(res.Error.Info)
+		}
+		// The pages have the normalized titles, ex: "File:Ada_Lovelace.jpg" becomes "File:Ada Lovelace.jpg"
+		normalized := map[string]string{}
+		for _, n := range res.Query.Normalize {
+			normalized[n.From] = n.To
+		}
+		pages := map[string]models.InnerPage{}
+		for _, p := range res.Query.Page {
+			pages[p.Title] = p
+		}
+		for _, title := range titles[start:end] {
+			if to, ok := normalized[title]; ok {
+				title = to
+			}
+			if p, ok := pages[title]; ok && len(p.ImageInfo) > 0 {
+				images = append(images, makeImage(p.Title, p.ImageInfo[0]))
+			}
+		}
+	}
+	return images, nil
+}
+
+func imageInfoArgs(thumbWidth int) map[string]string {
+	args := map[string]string{
+		"action":              "query",
+		"prop":                "imageinfo",
+		"iiprop":              "url|size|mime|sha1|extmetadata",
+		"iiextmetadatafilter": imageMetadataFilter,
+	}
+	if thumbWidth > 0 {
+		args["iiurlwidth"] = strconv.Itoa(thumbWidth)
+	}
+	return args
+}
+
+func makeImage(title string, info models.InnerImageInfo) Image {
+	meta := func(key string) string {
+		v, ok := info.ExtMetadata[key]
+		if !ok || v.Value == nil {
+			return ""
+		}
+		return strings.TrimSpace(html.UnescapeString(htmlTagRegex.ReplaceAllString(fmt.Sprint(v.Value), "")))
+	}
+	return Image{
+		Title:          title,
+		URL:            info.URL,
+		DescriptionURL: info.DescriptionURL,
+		Width:          info.Width,
+		Height:         info.Height,
+		Size:           info.Size,
+		MIME:           info.Mime,
+		SHA1:           info.SHA1,
+		ThumbURL:       info.ThumbURL,
+		ThumbWidth:     info.ThumbWidth,
+		ThumbHeight:    info.ThumbHeight,
+		License:        meta("LicenseShortName"),
+		LicenseURL:     meta("LicenseUrl"),
+		Artist:         meta("Artist"),
+		Credit:         meta("Credit"),
+		Description:    meta("ImageDescription"),
+	}
+}
+
+/*
+Return the images that pass the ExcludeIcons and ExcludeSVG filters of the options
+*/
+func FilterImages(images []Image, opts ImageOptions) []Image {
+	minSize := opts.MinSize
+	if minSize <= 0 {
+		minSize = DefaultIconSize
+	}
+	res := make([]Image, 0, len(images))
+	for _, img := range images {
+		if opts.ExcludeSVG && img.MIME == "image/svg+xml" {
+			continue
+		}
+		if opts.ExcludeIcons && IsIcon(img, minSize) {
+			continue
+		}
+		res = append(res, img)
+	}
+	return res
+}
+
+/*
+Return true if the image looks like an icon: smaller than minSize on both sides, or named like a flag or an icon
+*/
+func IsIcon(img Image, minSize int) bool {
+	if img.Width > 0 && img.Height > 0 && img.Width < minSize && img.Height < minSize {
+		return true
+	}
+	return iconTitleRegex.MatchString(strings.ToLower(strings.ReplaceAll(img.Title, "_", " ")))
+}
@@ -0,0 +1,111 @@
+package page
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Optional info props accepted by LoadInfo
+const (
+	InfoProtection       = "protection"
+	InfoTalkID           = "talkid"
+	InfoWatchers         = "watchers"
+	InfoVisitingWatchers = "visitingwatchers"
+	InfoDisplayTitle     = "displaytitle"
+	// Not a real inprop: prop=info always tells if the page is new
+	InfoNew = "new"
+)
+
+// A protection of a page
+type Protection struct {
+	Type   string    `json:"type"`   // The protected action: edit, move, upload or create
+	Level  string    `json:"level"`  // The group allowed to do the action: autoconfirmed, extendedconfirmed, sysop...
+	Expiry time.Time `json:"expiry"` // Zero when the protection is infinite
+}
+
+/*
+Reload the info fields of the page (Touched, LastRevID, Length, URLs...)
+
+Keyword arguments:
+
+* props: Optional info props to request as well: InfoProtection, InfoTalkID, InfoWatchers,
+InfoVisitingWatchers, InfoDisplayTitle or InfoNew
+
+Return:
+
+* Error
+*/
+func (page *WikipediaPage) LoadInfo(props ...string) error {
+	inprop := []string{"url"}
+	for _, p := range props {
+		if p != InfoNew && !utils.Isin(inprop, p) {
+			inprop = append(inprop, p)
+		}
+	}
+	args := map[string]string{
+		"action": "query",
+		"prop":   "info",
+		"inprop": strings.Join(inprop, "|"),
+		"titles": page.Title,
+	}
+	res, err := page.request(args)
+	if err != nil {
+		return err
+	}
+	if res.Error.Code != "" {
+		return errors.New(res.Error.Info)
+	}
+	for _, v := range res.Query.Page {
+		if v.PageID == 0 {
+			return errors.New("missing")
+		}
+		page.setInfo(v)
+		return nil
+	}
+	return errors.New("missing")
+}
+
+// Copy the prop=info fields of the API result into the page
+func (page *WikipediaPage) setInfo(info models.InnerPage) {
+	page.URL = info.FullURL
+	page.Touched = parseTimestamp(info.Touched)
+	if page.LastRevID != info.LastRevid {
+		// The timestamp of the new revision is not in prop=info
+		page.LastRevTimestamp = time.Time{}
+	}
+	page.LastRevID = info.LastRevid
+	page.Length = info.Length
+	page.ContentModel = info.ContentModel
+	page.PageLanguage = info.PageLanguage
+	page.PageLanguageDir = info.PageLanguageDir
+	page.CanonicalURL = info.CanonicalURL
+	page.EditURL = info.EditURL
+	page.TalkID = info.TalkID
+	page.Watchers = info.Watchers
+	page.VisitingWatchers = info.VisitingWatchers
+	page.DisplayTitle = info.DisplayTitle
+	page.New = info.New != nil
+	if info.Protection != nil {
+		page.Protection = make([]Protection, 0, len(info.Protection))
+		for _, p := range info.Protection {
+			page.Protection = append(page.Protection, Protection{
+				Type:   p.Type,
+				Level:  p.Level,
+				Expiry: parseTimestamp(p.Expiry),
+			})
+		}
+	}
+}
+
+// Parse an API timestamp. Returns the zero time for empty or "infinity" values
+func parseTimestamp(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
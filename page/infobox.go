@@ -0,0 +1,321 @@
+package page
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anaskhan96/soup"
+	"github.com/trietmn/go-wiki/utils"
+	"github.com/trietmn/go-wiki/wikitext"
+	"golang.org/x/net/html"
+)
+
+var (
+	// Templates that are infoboxes even though their name does not start with "Infobox"
+	infoboxTemplates = []string{"Taxobox", "Automatic taxobox", "Speciesbox", "Subspeciesbox", "Chembox", "Drugbox", "Geobox"}
+	// Date templates whose 3 first positional parameters are year, month and day
+	dateTemplates = []string{"birth date", "birth date and age", "death date", "death date and age", "start date", "start date and age", "end date", "film date", "release date", "dob"}
+	// Templates that only wrap their first positional parameter
+	wrapperTemplates = []string{"nowrap", "nobr", "small", "big", "lang", "nobold", "noitalic", "abbr", "sortname"}
+	// Templates whose positional parameters are the items of a list
+	listTemplates = []string{"ubl", "unbulleted list", "plainlist", "plain list", "flatlist", "flat list", "hlist", "collapsible list", "marriage", "url"}
+)
+
+// An infobox found in a page
+type Infobox struct {
+	Name   string         `json:"name"`   // Template name (ex: "Infobox person"), or the table class when parsed from HTML
+	Title  string         `json:"title"`  // Caption of the rendered infobox, if any
+	Fields []InfoboxField `json:"fields"` // Fields in the order they appear in the infobox
+}
+
+// A key/value pair of an infobox
+type InfoboxField struct {
+	Key   string   `json:"key"`
+	Raw   string   `json:"raw"`   // Raw wikitext of the value, or raw HTML when parsed from the rendered page
+	Text  string   `json:"text"`  // Value as plain text, lists are joined by ", "
+	Links []string `json:"links"` // Titles of the pages linked in the value
+}
+
+/*
+Return the field named `key` of the infobox, and whether it exists
+*/
+func (box Infobox) Get(key string) (InfoboxField, bool) {
+	for _, f := range box.Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return InfoboxField{}, false
+}
+
+/*
+Keys of the infobox in order
+*/
+func (box Infobox) Keys() []string {
+	res := make([]string, len(box.Fields))
+	for i, f := range box.Fields {
+		res[i] = f.Key
+	}
+	return res
+}
+
+/*
+Map of the infobox keys to the cleaned text values
+*/
+func (box Infobox) Map() map[string]string {
+	res := make(map[string]string, len(box.Fields))
+	for _, f := range box.Fields {
+		res[f.Key] = f.Text
+	}
+	return res
+}
+
+/*
+Get the raw wikitext of the page. Save it into the page.Wikitext for later use.
+The request is never cached, as the text and its revision are the base of Edit
+*/
+func (page *WikipediaPage) GetWikitext() (string, error) {
+	if page.Wikitext != "" {
+		return page.Wikitext, nil
+	}
+	args := map[string]string{
+		"action":  "query",
+		"prop":    "revisions",
+		"rvprop":  "content|ids|timestamp",
+		"rvlimit": "1",
+		"titles":  page.Title,
+		// A cached revision would be an outdated edit base, with a wrong starttimestamp
+		utils.NoCacheArg: "",
+	}
+	loadedAt := time.Now().UTC()
+	res, err := page.request(args)
+	if err != nil {
+		return "", err
+	}
+	if res.Error.Code != "" {
+		return "", errors.New(res.Error.Info)
+	}
+	revisions := res.Query.Page[fmt.Sprint(page.PageID)].Revision
+	if len(revisions) == 0 {
+		return "", errors.New("the page has no revision")
+	}
+	page.Wikitext, _ = revisions[0]["*"].(string)
+	revid, _ := revisions[0]["revid"].(float64)
+	timestamp, _ := revisions[0]["timestamp"].(string)
+	page.WikitextRevID = int(revid)
+	page.WikitextTimestamp = parseTimestamp(timestamp)
+	page.WikitextLoadedAt = loadedAt
+	return page.Wikitext, nil
+}
+
+/*
+Get the infoboxes of the page. Save them into the page.Infobox for later use
+
+The infoboxes are read from the page wikitext. If no infobox template is found
+(the infobox may be built by a module for example), the rendered `table.infobox` of the HTML is used instead
+*/
+func (page *WikipediaPage) GetInfobox() ([]Infobox, error) {
+	if page.CheckedInfobox {
+		return page.Infobox, nil
+	}
+	text, err := page.GetWikitext()
+	if err != nil {
+		return []Infobox{}, err
+	}
+	page.Infobox = ParseInfoboxWikitext(text)
+	if len(page.Infobox) == 0 {
+		content, err := page.GetHTML()
+		if err != nil {
+			return []Infobox{}, err
+		}
+		page.Infobox = ParseInfoboxHTML(content)
+	}
+	page.CheckedInfobox = true
+	return page.Infobox, nil
+}
+
+/*
+Return true if the template `name` is an infobox
+*/
+func IsInfoboxTemplate(name string) bool {
+	name = wikitext.NormalizeTitle(name)
+	return strings.HasPrefix(strings.ToLower(name), "infobox") || utils.Isin(infoboxTemplates, name)
+}
+
+/*
+Extract the infoboxes of a wikitext, including the ones embedded in other infoboxes
+*/
+func ParseInfoboxWikitext(text string) []Infobox {
+	res := []Infobox{}
+	for _, t := range wikitext.Parse(text).Templates() {
+		name := t.TemplateName()
+		if !IsInfoboxTemplate(name) {
+			continue
+		}
+		box := Infobox{Name: name, Fields: []InfoboxField{}}
+		for _, p := range t.Params {
+			if !p.Showkey {
+				continue
+			}
+			value := p.Value
+			field := InfoboxField{
+				Key:   p.Key(),
+				Raw:   strings.TrimSpace(value.String()),
+				Text:  cleanWikitextValue(value),
+				Links: []string{},
+			}
+			for _, l := range value.Wikilinks() {
+				if title := l.Title(); title != "" && !isMediaLink(title) && !utils.Isin(field.Links, title) {
+					field.Links = append(field.Links, title)
+				}
+			}
+			box.Fields = append(box.Fields, field)
+		}
+		if f, ok := box.Get("name"); ok {
+			box.Title = f.Text
+		}
+		res = append(res, box)
+	}
+	return res
+}
+
+func isMediaLink(title string) bool {
+	lower := strings.ToLower(title)
+	return strings.HasPrefix(lower, "file:") || strings.HasPrefix(lower, "image:") || strings.HasPrefix(lower, "category:")
+}
+
+/*
+Plain text of an infobox value. Common formatting templates (dates, lists, nowrap...) are rendered,
+the other templates, references and comments are dropped. Multiple values are joined by ", "
+*/
+func cleanWikitextValue(nodes wikitext.Nodes) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		if t, ok := n.(*wikitext.Template); ok {
+			b.WriteString(templateText(t))
+			continue
+		}
+		if t, ok := n.(*wikitext.Tag); ok && !strings.EqualFold(t.Name, "ref") && !strings.EqualFold(t.Name, "br") {
+			b.WriteString(cleanWikitextValue(t.Contents))
+			continue
+		}
+		b.WriteString(n.Text())
+	}
+	lines := strings.Split(b.String(), "\n")
+	res := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*#:;"))
+		if line != "" {
+			res = append(res, spaceRegex.ReplaceAllString(line, " "))
+		}
+	}
+	return strings.Join(res, ", ")
+}
+
+// Render the templates commonly used in infobox values
+func templateText(t *wikitext.Template) string {
+	name := strings.ToLower(t.TemplateName())
+	positional := func(i int) string {
+		if p := t.Get(fmt.Sprint(i)); p != nil {
+			return cleanWikitextValue(p.Value)
+		}
+		return ""
+	}
+	switch {
+	case utils.Isin(dateTemplates, name):
+		y, m, d := positional(1), positional(2), positional(3)
+		if m == "" {
+			return y
+		}
+		if d == "" {
+			return fmt.Sprintf("%v-%02v", y, m)
+		}
+		return fmt.Sprintf("%v-%02v-%02v", y, m, d)
+	case name == "birth year and age" || name == "death year and age":
+		return positional(1)
+	case utils.Isin(wrapperTemplates, name):
+		if name == "lang" {
+			return positional(2)
+		}
+		return positional(1)
+	case utils.Isin(listTemplates, name):
+		items := []string{}
+		for _, p := range t.Params {
+			if !p.Showkey {
+				if v := cleanWikitextValue(p.Value); v != "" {
+					items = append(items, v)
+				}
+			}
+		}
+		return strings.Join(items, "\n")
+	case name == "convert" || name == "cvt":
+		return strings.TrimSpace(positional(1) + " " + positional(2))
+	}
+	return ""
+}
+
+/*
+Extract the infoboxes (`table.infobox`) of the rendered HTML of a page
+*/
+func ParseInfoboxHTML(content string) []Infobox {
+	res := []Infobox{}
+	doc := soup.HTMLParse(content)
+	if doc.Error != nil {
+		return res
+	}
+	for _, table := range doc.FindAll("table", "class", "infobox") {
+		box := Infobox{Name: strings.TrimSpace(table.Attrs()["class"]), Fields: []InfoboxField{}}
+		if caption := table.Find("caption"); caption.Error == nil {
+			box.Title = nodeText(caption.Pointer)
+		}
+		for _, tr := range table.FindAll("tr") {
+			// Skip the rows of nested tables, they belong to their own infobox
+			if closestTable(tr.Pointer) != table.Pointer {
+				continue
+			}
+			var label, value *html.Node
+			for c := tr.Pointer.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type != html.ElementNode {
+					continue
+				}
+				if c.Data == "th" && label == nil {
+					label = c
+				} else if c.Data == "td" && value == nil {
+					value = c
+				}
+			}
+			if label != nil && value == nil && box.Title == "" && hasClass(label, "infobox-above") {
+				box.Title = nodeText(label)
+			}
+			if label == nil || value == nil {
+				continue
+			}
+			field := InfoboxField{
+				Key:   nodeText(label),
+				Raw:   strings.TrimSpace(renderHTML(value)),
+				Text:  strings.ReplaceAll(nodeText(value), "\n", ", "),
+				Links: []string{},
+			}
+			for _, a := range (soup.Root{Pointer: value}).FindAll("a") {
+				if title := linkTitle(a.Pointer); title != "" && !isMediaLink(title) && !utils.Isin(field.Links, title) {
+					field.Links = append(field.Links, title)
+				}
+			}
+			box.Fields = append(box.Fields, field)
+		}
+		res = append(res, box)
+	}
+	return res
+}
+
+// Return the closest <table> ancestor of a node
+func closestTable(n *html.Node) *html.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "table" {
+			return p
+		}
+	}
+	return nil
+}
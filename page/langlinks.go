@@ -0,0 +1,89 @@
+package page
+
+import (
+	"fmt"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// A link to the same subject in another language edition of Wikipedia
+type LangLink struct {
+	Lang     string `json:"lang"`     // Language code, ex: "de"
+	Title    string `json:"title"`    // Title of the page in that language
+	URL      string `json:"url"`      // Full URL of the page
+	LangName string `json:"langname"` // Name of the language in the language of the request
+	Autonym  string `json:"autonym"`  // Name of the language in that language, ex: "Deutsch"
+}
+
+/*
+Get the interlanguage links of the page. Save it into the page.LangLinks for later use
+*/
+func (page *WikipediaPage) GetLangLinks() ([]LangLink, error) {
+	if page.LangLinks != nil {
+		return page.LangLinks, nil
+	}
+	args := map[string]string{
+		"action":  "query",
+		"prop":    "langlinks",
+		"llprop":  "url|langname|autonym",
+		"lllimit": "max",
+	}
+	res, err := page.ContinuedQuery(args)
+	if err != nil {
+		return []LangLink{}, err
+	}
+	result := make([]LangLink, 0, len(res))
+	for _, v := range res {
+		link := v.(models.InnerLangLink)
+		result = append(result, LangLink{
+			Lang:     link.Lang,
+			Title:    link.Title,
+			URL:      link.URL,
+			LangName: link.LangName,
+			Autonym:  link.Autonym,
+		})
+	}
+	page.LangLinks = result
+	return page.LangLinks, nil
+}
+
+/*
+Return the interlanguage link to the `lang` edition of Wikipedia, and whether it exists
+*/
+func (page *WikipediaPage) GetLangLink(lang string) (LangLink, bool) {
+	links, err := page.GetLangLinks()
+	if err != nil {
+		return LangLink{}, false
+	}
+	for _, link := range links {
+		if link.Lang == lang {
+			return link, true
+		}
+	}
+	return LangLink{}, false
+}
+
+/*
+Load the same page from the `lang` edition of Wikipedia, ex: page.InLanguage("de").
+
+The global language and the cache are left unchanged. Every method of the returned page
+requests the `lang` edition.
+*/
+func (page *WikipediaPage) InLanguage(lang string) (WikipediaPage, error) {
+	current := page.Language
+	if current == "" {
+		current = utils.WikiLanguage
+	}
+	if lang == current {
+		return *page, nil
+	}
+	if _, err := page.GetLangLinks(); err != nil {
+		return WikipediaPage{}, err
+	}
+	link, ok := page.GetLangLink(lang)
+	if !ok {
+		return WikipediaPage{}, fmt.Errorf("the page has no %v version", lang)
+	}
+	return MakeWikipediaPageInLanguage(lang, -1, link.Title, "", true)
+}
@@ -0,0 +1,378 @@
+package page
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anaskhan96/soup"
+	"github.com/trietmn/go-wiki/utils"
+	"golang.org/x/net/html"
+)
+
+var (
+	markdownEscaper    = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+	blankLinesRegex    = regexp.MustCompile(`\n{3,}`)
+	markdownBlockTags  = []string{"p", "div", "ul", "ol", "dl", "blockquote", "pre", "table", "hr", "h1", "h2", "h3", "h4", "h5", "h6", "figure", "section", "center", "main", "article", "body"}
+	navboxClasses      = []string{"navbox", "vertical-navbox", "navbox-styles", "sidebar", "sistersitebox", "portalbox", "ambox", "metadata"}
+	hatnoteClasses     = []string{"hatnote", "dablink", "rellink"}
+	alwaysDropClasses  = []string{"toc", "mw-empty-elt", "shortdescription", "mw-jump-link", "printfooter", "catlinks"}
+	referenceClasses   = []string{"reference", "mw-cite-backlink"}
+	headingLevelLookup = map[string]int{"h1": 1, "h2": 2, "h3": 3, "h4": 4, "h5": 5, "h6": 6}
+)
+
+// Options of the Markdown rendering. The zero value drops every navigational element
+type MarkdownOptions struct {
+	BaseURL              string // Used to make the links absolute, ex: "https://en.wikipedia.org". Default is the page host
+	KeepNavboxes         bool   // Keep the navigation boxes, sidebars and maintenance boxes
+	KeepEditLinks        bool   // Keep the [edit] links of the headings
+	KeepReferenceMarkers bool   // Keep the footnote markers like [1]
+	KeepHatnotes         bool   // Keep the hatnotes like "For other uses, see ..."
+	KeepImages           bool   // Render the images as ![caption](url)
+}
+
+type markdownRenderer struct {
+	opts MarkdownOptions
+}
+
+/*
+Render the page HTML into clean Markdown. Headings, lists, links, tables,
+blockquotes and emphasis are kept, links are made absolute
+*/
+func (page *WikipediaPage) GetMarkdown(opts MarkdownOptions) (string, error) {
+	content, err := page.GetHTML()
+	if err != nil {
+		return "", err
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = baseURL(page.URL)
+	}
+	return RenderMarkdown(content, opts), nil
+}
+
+// Return the scheme and host of a URL, or the ones of the API when it cannot be parsed
+func baseURL(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host
+	}
+	api := strings.Replace(utils.WikiURL, "%v", utils.WikiLanguage, 1)
+	if u, err := url.Parse(api); err == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return ""
+}
+
+/*
+Convert an HTML document or fragment into Markdown
+*/
+func RenderMarkdown(content string, opts MarkdownOptions) string {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	body := findElement(doc, "body")
+	if body == nil {
+		body = doc
+	}
+	r := markdownRenderer{opts: opts}
+	res := strings.Join(r.blocks(body), "\n\n")
+	return strings.TrimSpace(blankLinesRegex.ReplaceAllString(res, "\n\n")) + "\n"
+}
+
+// Return the first element named `name` in the tree of n
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if res := findElement(c, name); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// Return true if the element must not be rendered
+func (r markdownRenderer) skip(n *html.Node) bool {
+	switch n.Type {
+	case html.CommentNode:
+		return true
+	case html.ElementNode:
+	default:
+		return false
+	}
+	if n.Data == "style" || n.Data == "script" || n.Data == "link" || n.Data == "meta" || getAttr(n, "id") == "toc" {
+		return true
+	}
+	has := func(classes []string) bool {
+		for _, c := range classes {
+			if hasClass(n, c) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has(alwaysDropClasses):
+		return true
+	case !r.opts.KeepNavboxes && has(navboxClasses):
+		return true
+	case !r.opts.KeepHatnotes && has(hatnoteClasses):
+		return true
+	case !r.opts.KeepEditLinks && hasClass(n, "mw-editsection"):
+		return true
+	case !r.opts.KeepReferenceMarkers && has(referenceClasses):
+		return true
+	case !r.opts.KeepImages && (n.Data == "img" || n.Data == "figure" || hasClass(n, "thumb") || hasClass(n, "gallery")):
+		return true
+	}
+	return false
+}
+
+func isMarkdownBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && utils.Isin(markdownBlockTags, n.Data)
+}
+
+/*
+Render the children of n as Markdown blocks. Consecutive inline children are grouped into paragraphs
+*/
+func (r markdownRenderer) blocks(n *html.Node) []string {
+	res := []string{}
+	var paragraph strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(paragraph.String()); text != "" {
+			res = append(res, text)
+		}
+		paragraph.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if r.skip(c) {
+			continue
+		}
+		if !isMarkdownBlock(c) {
+			paragraph.WriteString(r.inline(c))
+			continue
+		}
+		flush()
+		res = append(res, r.block(c)...)
+	}
+	flush()
+	return res
+}
+
+func (r markdownRenderer) block(n *html.Node) []string {
+	if level, ok := headingLevelLookup[n.Data]; ok {
+		text := strings.TrimSpace(r.inlineChildren(n))
+		if text == "" {
+			return nil
+		}
+		return []string{strings.Repeat("#", level) + " " + text}
+	}
+	switch n.Data {
+	case "p":
+		if text := strings.TrimSpace(r.inlineChildren(n)); text != "" {
+			return []string{text}
+		}
+		return nil
+	case "ul", "ol", "dl":
+		if list := r.list(n, 0); list != "" {
+			return []string{list}
+		}
+		return nil
+	case "blockquote":
+		inner := strings.Join(r.blocks(n), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		return []string{"> " + strings.ReplaceAll(inner, "\n", "\n> ")}
+	case "pre":
+		return []string{"```\n" + strings.Trim(textContent(n), "\n") + "\n```"}
+	case "hr":
+		return []string{"---"}
+	case "table":
+		if table := r.table(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case "figure":
+		return r.figure(n)
+	}
+	return r.blocks(n)
+}
+
+/*
+Render a list. Nested lists are indented by 2 spaces per level
+*/
+func (r markdownRenderer) list(n *html.Node, depth int) string {
+	lines := []string{}
+	indent := strings.Repeat("  ", depth)
+	index := 0
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || r.skip(li) {
+			continue
+		}
+		index++
+		marker := "- "
+		switch {
+		case n.Data == "ol":
+			marker = strconv.Itoa(index) + ". "
+		case li.Data == "dt":
+			marker = ""
+		case li.Data == "dd":
+			marker = ": "
+		}
+		var text strings.Builder
+		nested := []string{}
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if r.skip(c) {
+				continue
+			}
+			if c.Type == html.ElementNode && (c.Data == "ul" || c.Data == "ol" || c.Data == "dl") {
+				if sub := r.list(c, depth+1); sub != "" {
+					nested = append(nested, sub)
+				}
+				continue
+			}
+			text.WriteString(r.inline(c))
+		}
+		line := strings.TrimSpace(text.String())
+		if li.Data == "dt" && line != "" {
+			line = "**" + line + "**"
+		}
+		if line != "" {
+			lines = append(lines, indent+marker+strings.ReplaceAll(line, "\n", "\n"+indent+"  "))
+		}
+		lines = append(lines, nested...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+Render a table with the rowspan and colspan cells expanded. The first header row
+(or the first row) is used as the Markdown header
+*/
+func (r markdownRenderer) table(n *html.Node) string {
+	t := parseTable(soup.Root{Pointer: n, NodeValue: n.Data})
+	rows := append(append([][]string{}, t.Header...), t.Rows...)
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return ""
+	}
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+	line := func(cells []string) string {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = escape.Replace(c)
+		}
+		return "| " + strings.Join(escaped, " | ") + " |"
+	}
+	lines := []string{}
+	if t.Caption != "" {
+		lines = append(lines, "**"+t.Caption+"**", "")
+	}
+	lines = append(lines, line(rows[0]))
+	separator := make([]string, len(rows[0]))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	lines = append(lines, line(separator))
+	for _, row := range rows[1:] {
+		lines = append(lines, line(row))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r markdownRenderer) figure(n *html.Node) []string {
+	img := findElement(n, "img")
+	if img == nil {
+		return r.blocks(n)
+	}
+	caption := ""
+	if c := findElement(n, "figcaption"); c != nil {
+		caption = strings.TrimSpace(r.inlineChildren(c))
+	}
+	if caption == "" {
+		caption = getAttr(img, "alt")
+	}
+	return []string{"![" + caption + "](" + r.absoluteURL(getAttr(img, "src")) + ")"}
+}
+
+func (r markdownRenderer) inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(r.inline(c))
+	}
+	return b.String()
+}
+
+// Wrap the text with a Markdown delimiter, keeping the surrounding spaces outside of it
+func wrapInline(text string, delimiter string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + delimiter + trimmed + delimiter + text[start+len(trimmed):]
+}
+
+func (r markdownRenderer) inline(n *html.Node) string {
+	if r.skip(n) {
+		return ""
+	}
+	switch n.Type {
+	case html.TextNode:
+		return markdownEscaper.Replace(spaceRegex.ReplaceAllString(strings.ReplaceAll(n.Data, "\n", " "), " "))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	switch n.Data {
+	case "b", "strong":
+		return wrapInline(r.inlineChildren(n), "**")
+	case "i", "em":
+		return wrapInline(r.inlineChildren(n), "*")
+	case "code", "kbd", "tt", "samp":
+		return "`" + textContent(n) + "`"
+	case "br":
+		return "  \n"
+	case "img":
+		return "![" + getAttr(n, "alt") + "](" + r.absoluteURL(getAttr(n, "src")) + ")"
+	case "a":
+		text := r.inlineChildren(n)
+		href := getAttr(n, "href")
+		if strings.TrimSpace(text) == "" || href == "" || strings.HasPrefix(href, "#") || hasClass(n, "new") {
+			return text
+		}
+		return "[" + strings.TrimSpace(text) + "](" + r.absoluteURL(href) + ")"
+	}
+	if isMarkdownBlock(n) {
+		return " " + strings.Join(r.blocks(n), " ") + " "
+	}
+	return r.inlineChildren(n)
+}
+
+// Make a link of the page HTML absolute
+func (r markdownRenderer) absoluteURL(href string) string {
+	switch {
+	case strings.HasPrefix(href, "//"):
+		return "https:" + href
+	case strings.HasPrefix(href, "/"):
+		return r.opts.BaseURL + href
+	case strings.HasPrefix(href, "./"):
+		// Parsoid links are relative to the article path
+		return r.opts.BaseURL + "/wiki/" + strings.TrimPrefix(href, "./")
+	}
+	return strings.ReplaceAll(href, " ", "%20")
+}
+
+// Raw text of a node, without any cleaning
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Result after we parse the response of Wikipedia API.
@@ -31,8 +32,49 @@ type WikipediaPage struct {
 	Link           []string         `json:"links"`
 	Category       []string         `json:"categories"`
 	Section        []string         `json:"sections"`
+	SectionTree    []*Section       `json:"sectiontree"`
 	SectionOffset  map[string][]int `json:"sectionoffset"`
 	Disambiguation []string         `json:"disambiguation"`
+	Wikitext       string           `json:"wikitext"`
+	CheckedInfobox bool             `json:"checkedinfobox"`
+	Infobox        []Infobox        `json:"infobox"`
+	Citations      []Citation       `json:"citations"`
+	// Revision of page.Wikitext and time it was loaded, used by Edit to detect the edit conflicts
+	WikitextRevID     int       `json:"wikitextrevid"`
+	WikitextTimestamp time.Time `json:"wikitexttimestamp"`
+	WikitextLoadedAt  time.Time `json:"wikitextloadedat"`
+	// Fields of prop=info, see LoadInfo for the optional ones
+	Touched          time.Time    `json:"touched"`
+	LastRevID        int          `json:"lastrevid"`
+	LastRevTimestamp time.Time    `json:"lastrevtimestamp"` // Timestamp of the LastRevID revision, only set by rest.ApplySummary
+	Length           int          `json:"length"`
+	ContentModel     string       `json:"contentmodel"`
+	PageLanguage     string       `json:"pagelanguage"`
+	PageLanguageDir  string       `json:"pagelanguagedir"`
+	CanonicalURL     string       `json:"canonicalurl"`
+	EditURL          string       `json:"editurl"`
+	Protection       []Protection `json:"protection"`
+	TalkID           int          `json:"talkid"`
+	Watchers         int          `json:"watchers"`
+	VisitingWatchers int          `json:"visitingwatchers"`
+	DisplayTitle     string       `json:"displaytitle"`
+	New              bool         `json:"new"`
+	Language         string       `json:"language"` // Language edition of the page. Empty for utils.WikiLanguage
+	LangLinks        []LangLink   `json:"langlinks"`
+	WikidataID       string       `json:"wikidataid"`
+}
+
+// Make an API request on the language edition of the page
+func (page *WikipediaPage) request(args map[string]string) (models.RequestResult, error) {
+	return utils.WikiRequester(page.languageArgs(args))
+}
+
+// Add the language of the page to the request arguments
+func (page *WikipediaPage) languageArgs(args map[string]string) map[string]string {
+	if page.Language != "" {
+		args[utils.LangArg] = page.Language
+	}
+	return args
 }
 
 /*
@@ -57,7 +99,7 @@ func (page *WikipediaPage) GetContent() (string, error) {
 		"rvprop":      "ids",
 		"titles":      page.Title,
 	}
-	res, err := utils.WikiRequester(args)
+	res, err := page.request(args)
 	if err != nil {
 		return "", err
 	}
@@ -88,7 +130,7 @@ func (page *WikipediaPage) GetHTML() (string, error) {
 		"rvparse": "",
 		"titles":  page.Title,
 	}
-	res, err := utils.WikiRequester(args)
+	res, err := page.request(args)
 	if err != nil {
 		return "", err
 	}
@@ -148,7 +190,7 @@ func (page *WikipediaPage) GetSummary() (string, error) {
 		"exintro":     "",
 		"titles":      page.Title,
 	}
-	res, err := utils.WikiRequester(args)
+	res, err := page.request(args)
 	if err != nil {
 		return "", err
 	}
@@ -172,7 +214,7 @@ func (page *WikipediaPage) ContinuedQuery(args map[string]string) ([]interface{}
 		new_args := utils.CopyMap(args)
 		utils.UpdateMap(new_args, last)
 
-		res, err := utils.WikiRequester(new_args)
+		res, err := page.request(new_args)
 		if err != nil {
 			return result, err
 		}
@@ -194,6 +236,10 @@ func (page *WikipediaPage) ContinuedQuery(args map[string]string) ([]interface{}
 				for _, v := range temp {
 					result = append(result, v["*"])
 				}
+			} else if prop == "langlinks" {
+				for _, v := range res.Query.Page[strconv.Itoa(page.PageID)].LangLinks {
+					result = append(result, v)
+				}
 			} else {
 				temp := []map[string]interface{}{}
 				switch prop {
@@ -234,14 +280,14 @@ func (page *WikipediaPage) GetImagesURL() ([]string, error) {
 	}
 
 	res, err := page.ContinuedQuery(args)
-	if err != nil && len(res) == 0 {
+	if err != nil {
 		return []string{}, err
 	}
 	result := make([]string, 0, 7)
 	for _, v := range res {
 		temp := v.(models.InnerPage).ImageInfo
 		if len(temp) > 0 {
-			result = append(result, temp[0]["url"])
+			result = append(result, temp[0].URL)
 		}
 	}
 	page.CheckedImage = true
@@ -263,7 +309,7 @@ func (page *WikipediaPage) GetCoordinate() ([]float64, error) {
 		"titles":  page.Title,
 	}
 
-	res, err := utils.WikiRequester(args)
+	res, err := page.request(args)
 	if err != nil {
 		return []float64{}, err
 	}
@@ -360,34 +406,27 @@ func (page *WikipediaPage) GetSectionList() ([]string, error) {
 	if len(page.Section) > 0 {
 		return page.Section, nil
 	}
-	args := map[string]string{
-		"action": "parse",
-		"prop":   "sections",
-	}
-	if page.Title != "" {
-		args["page"] = page.Title
-	}
-	res, err := utils.WikiRequester(args)
+	tree, err := page.GetSectionTree()
 	if err != nil {
 		return []string{}, err
 	}
-	if res.Error.Code != "" {
-		return []string{}, errors.New(res.Error.Info)
-	}
-	for _, v := range res.Parse["sections"].([]interface{}) {
-		page.Section = append(page.Section, v.(map[string]interface{})["line"].(string))
-	}
+	// The sections in the order of the page, subsections included
+	findSection(tree, func(s *Section) bool {
+		page.Section = append(page.Section, s.Title)
+		return false
+	})
 	return page.Section, nil
 }
 
+/*
+Get the plain text of a section, without its subsections. The section is found by title or anchor
+like FindSection, so the duplicated titles are reached with their anchor, ex: "Notes_2"
+*/
 func (page *WikipediaPage) GetSection(section string) (string, error) {
-	sections, err := page.GetSectionList()
+	target, err := page.FindSection(section)
 	if err != nil {
 		return "", err
 	}
-	if !utils.Isin(sections, section) {
-		return "", errors.New("section not exist")
-	}
 	content, err := page.GetContent()
 	if err != nil {
 		return "", err
@@ -395,24 +434,40 @@ func (page *WikipediaPage) GetSection(section string) (string, error) {
 	if page.SectionOffset == nil {
 		page.SectionOffset = map[string][]int{}
 	}
-	if value, ok := page.SectionOffset[section]; ok {
-		return content[value[0]:value[1]], nil
+	if value, ok := page.SectionOffset[target.Anchor]; ok {
+		return strings.TrimSpace(content[value[0]:value[1]]), nil
+	}
+	// Number of the sections before the target with the same heading
+	occurrence := 0
+	findSection(page.SectionTree, func(s *Section) bool {
+		if s == target {
+			return true
+		}
+		if s.Title == target.Title && s.Level == target.Level {
+			occurrence++
+		}
+		return false
+	})
+	start, end := -1, len(content)
+	for _, m := range sectionHeadingRegex.FindAllStringSubmatchIndex(content, -1) {
+		if start >= 0 {
+			end = m[0]
+			break
+		}
+		if m[3]-m[2] == target.Level && content[m[4]:m[5]] == target.Title {
+			if occurrence == 0 {
+				start = m[1]
+			}
+			occurrence--
+		}
 	}
-	sectiontitle := "== " + section + " =="
-	start := strings.Index(content, sectiontitle) + len(sectiontitle)
 	// If you cannot find the section in the content (but it's there in the API for some reason)
-	if start < len(sectiontitle) {
-		page.SectionOffset[section] = []int{0, 0}
+	if start < 0 {
+		page.SectionOffset[target.Anchor] = []int{0, 0}
 		return "", nil
 	}
-	end := strings.Index(content[start:], "==")
-	if end == -1 {
-		page.SectionOffset[section] = []int{start, len(content)}
-		return content[start:], nil
-	}
-	end += start
-	page.SectionOffset[section] = []int{start, end}
-	return strings.TrimSpace(strings.TrimLeft(content[start:end], "=")), nil
+	page.SectionOffset[target.Anchor] = []int{start, end}
+	return strings.TrimSpace(content[start:end]), nil
 }
 
 /*
@@ -421,12 +476,20 @@ func (page *WikipediaPage) GetSection(section string) (string, error) {
 	    Confirm that page exists. If it's a disambiguation page, get a list of suggesting
 */
 func MakeWikipediaPage(pageid int, title string, originaltitle string, redirect bool) (WikipediaPage, error) {
-	page := WikipediaPage{}
+	return MakeWikipediaPageInLanguage("", pageid, title, originaltitle, redirect)
+}
+
+/*
+Same as MakeWikipediaPage, but load the page from the `lang` edition of Wikipedia
+without changing utils.WikiLanguage. Use "" for utils.WikiLanguage
+*/
+func MakeWikipediaPageInLanguage(lang string, pageid int, title string, originaltitle string, redirect bool) (WikipediaPage, error) {
+	page := WikipediaPage{Language: lang}
 	args := map[string]string{
 		"action":    "query",
 		"prop":      "info|pageprops",
 		"inprop":    "url",
-		"ppprop":    "disambiguation",
+		"ppprop":    "disambiguation|wikibase_item",
 		"redirects": "",
 	}
 	page.Title = title
@@ -440,7 +503,7 @@ func MakeWikipediaPage(pageid int, title string, originaltitle string, redirect
 	if originaltitle != "" {
 		page.OriginalTitle = originaltitle
 	}
-	res, err := utils.WikiRequester(args)
+	res, err := page.request(args)
 	if err != nil {
 		return page, err
 	}
@@ -464,6 +527,7 @@ func MakeWikipediaPage(pageid int, title string, originaltitle string, redirect
 		page.Title = target.Title
 		page.OriginalTitle = target.Title
 	}
+	page.WikidataID = target.PageProps["wikibase_item"]
 
 	if target.Missing == "" && index == "-1" {
 		return page, errors.New("missing")
@@ -483,7 +547,7 @@ func MakeWikipediaPage(pageid int, title string, originaltitle string, redirect
 		if tempstr != res.Query.Redirect[0].From {
 			return page, errors.New("an unexpected weird error, report me if it happened")
 		}
-		return MakeWikipediaPage(-1, res.Query.Redirect[0].To, "", redirect)
+		return MakeWikipediaPageInLanguage(lang, -1, res.Query.Redirect[0].To, "", redirect)
 	}
 
 	// If the page is a disambiguation page
@@ -496,7 +560,7 @@ func MakeWikipediaPage(pageid int, title string, originaltitle string, redirect
 			"rvlimit": strconv.Itoa(1),
 			"titles":  page.Title,
 		}
-		res, err = utils.WikiRequester(args)
+		res, err = page.request(args)
 		if err != nil {
 			return page, err
 		}
@@ -521,7 +585,7 @@ func MakeWikipediaPage(pageid int, title string, originaltitle string, redirect
 		return page, nil
 	}
 
-	page.URL = target.FullURL
+	page.setInfo(target)
 
 	return page, nil
 }
@@ -0,0 +1,180 @@
+package page
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/trietmn/go-wiki/wikitext"
+)
+
+var (
+	htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+	// Heading of a section in the plain text content, ex: "=== Title ==="
+	sectionHeadingRegex = regexp.MustCompile(`(?m)^(=+) *(.*?) *=+ *$`)
+)
+
+// A section of the page table of contents, with its subsections
+type Section struct {
+	Title      string     `json:"title"`
+	Level      int        `json:"level"`      // Heading level: 2 for "== Title ==", 3 for "=== Title ===", ...
+	TocLevel   int        `json:"toclevel"`   // Depth in the table of contents, starting at 1
+	Number     string     `json:"number"`     // Number in the table of contents, ex: "2.1"
+	Anchor     string     `json:"anchor"`     // Fragment of the section URL
+	Index      string     `json:"index"`      // Section index for action=parse&section=N. Starts with "T-" for transcluded sections
+	ByteOffset int        `json:"byteoffset"` // Offset of the heading in the page wikitext, -1 for transcluded sections
+	FromTitle  string     `json:"fromtitle"`  // Page the section comes from
+	Children   []*Section `json:"children"`
+	Wikitext   string     `json:"wikitext"` // Wikitext of the section and its subsections, set by GetSectionContent
+	Text       string     `json:"text"`     // Plain text of the section and its subsections, set by GetSectionContent
+}
+
+/*
+Get the sections of the page as a tree: the top level sections with their subsections as Children.
+Save it into the page.SectionTree for later use
+*/
+func (page *WikipediaPage) GetSectionTree() ([]*Section, error) {
+	if page.SectionTree != nil {
+		return page.SectionTree, nil
+	}
+	args := map[string]string{
+		"action": "parse",
+		"prop":   "sections",
+	}
+	if page.Title != "" {
+		args["page"] = page.Title
+	}
+	res, err := page.request(args)
+	if err != nil {
+		return []*Section{}, err
+	}
+	if res.Error.Code != "" {
+		return []*Section{}, errors.New(res.Error.Info)
+	}
+	sections, _ := res.Parse["sections"].([]interface{})
+	roots := []*Section{}
+	stack := []*Section{}
+	for _, v := range sections {
+		s := makeSection(v.(map[string]interface{}))
+		for len(stack) > 0 && stack[len(stack)-1].TocLevel >= s.TocLevel {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, s)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, s)
+		}
+		stack = append(stack, s)
+	}
+	page.SectionTree = roots
+	return page.SectionTree, nil
+}
+
+// Make a Section from an item of parse.sections
+func makeSection(v map[string]interface{}) *Section {
+	s := &Section{Children: []*Section{}, ByteOffset: -1}
+	s.Title, _ = v["line"].(string)
+	s.Title = strings.TrimSpace(htmlTagRegex.ReplaceAllString(s.Title, ""))
+	s.Number, _ = v["number"].(string)
+	s.Anchor, _ = v["anchor"].(string)
+	s.Index, _ = v["index"].(string)
+	s.FromTitle, _ = v["fromtitle"].(string)
+	if level, ok := v["level"].(string); ok {
+		s.Level, _ = strconv.Atoi(level)
+	}
+	if toclevel, ok := v["toclevel"].(float64); ok {
+		s.TocLevel = int(toclevel)
+	}
+	if offset, ok := v["byteoffset"].(float64); ok {
+		s.ByteOffset = int(offset)
+	}
+	return s
+}
+
+/*
+Find a section of the page by title or anchor, searching the subsections too.
+When multiple sections have the same title the first one is returned, use the anchor to get the others
+*/
+func (page *WikipediaPage) FindSection(title string) (*Section, error) {
+	tree, err := page.GetSectionTree()
+	if err != nil {
+		return nil, err
+	}
+	if s := findSection(tree, func(s *Section) bool { return s.Anchor == title }); s != nil {
+		return s, nil
+	}
+	if s := findSection(tree, func(s *Section) bool { return s.Title == title }); s != nil {
+		return s, nil
+	}
+	return nil, errors.New("section not exist")
+}
+
+func findSection(sections []*Section, match func(*Section) bool) *Section {
+	for _, s := range sections {
+		if match(s) {
+			return s
+		}
+		if res := findSection(s.Children, match); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+/*
+Load the wikitext and the plain text of a section, including its subsections,
+using its index. Save them into section.Wikitext and section.Text for later use.
+
+A transcluded section, with a "T-N" index, is loaded from the page it comes from
+*/
+func (page *WikipediaPage) GetSectionContent(section *Section) (string, error) {
+	if section.Wikitext != "" {
+		return section.Text, nil
+	}
+	args := map[string]string{
+		"action":  "parse",
+		"prop":    "wikitext",
+		"section": section.Index,
+		"page":    page.Title,
+	}
+	if strings.HasPrefix(section.Index, "T-") {
+		if section.FromTitle == "" {
+			return "", errors.New("unknown page of the transcluded section")
+		}
+		args["section"] = strings.TrimPrefix(section.Index, "T-")
+		args["page"] = section.FromTitle
+	}
+	res, err := page.request(args)
+	if err != nil {
+		return "", err
+	}
+	if res.Error.Code != "" {
+		return "", errors.New(res.Error.Info)
+	}
+	if content, ok := res.Parse["wikitext"].(map[string]interface{}); ok {
+		section.Wikitext, _ = content["*"].(string)
+	}
+	nodes := wikitext.Parse(section.Wikitext)
+	// Drop the heading of the section itself, keep the ones of the subsections
+	if len(nodes) > 0 {
+		if _, ok := nodes[0].(*wikitext.Heading); ok {
+			nodes = nodes[1:]
+		}
+	}
+	section.Text = cleanText(nodes.Text())
+	return section.Text, nil
+}
+
+/*
+Find a section by title or anchor and load its content together with its subsections
+*/
+func (page *WikipediaPage) GetSectionNode(title string) (*Section, error) {
+	section, err := page.FindSection(title)
+	if err != nil {
+		return nil, err
+	}
+	_, err = page.GetSectionContent(section)
+	return section, err
+}
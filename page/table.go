@@ -0,0 +1,229 @@
+package page
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/anaskhan96/soup"
+	"golang.org/x/net/html"
+)
+
+// Max colspan/rowspan accepted, to protect against broken markup
+const maxSpan = 1000
+
+// A table of a page, with the rowspan and colspan cells expanded into a regular grid
+type Table struct {
+	Caption string     `json:"caption"`
+	Class   string     `json:"class"`
+	Header  [][]string `json:"header"` // Header rows. The last one usually holds the column names
+	Rows    [][]string `json:"rows"`   // Data rows, all with the same number of cells as the header
+}
+
+/*
+Get all the `wikitable` tables of the page HTML
+*/
+func (page *WikipediaPage) GetTables() ([]Table, error) {
+	content, err := page.GetHTML()
+	if err != nil {
+		return []Table{}, err
+	}
+	return ParseTables(content, "wikitable"), nil
+}
+
+/*
+Extract the tables of an HTML document having the class `class`. Use "" to get every table.
+
+Cells spanning multiple rows or columns are copied into every position they cover,
+footnote markers like [1] are stripped and rows are padded to the width of the widest row
+*/
+func ParseTables(content string, class string) []Table {
+	res := []Table{}
+	doc := soup.HTMLParse(content)
+	if doc.Error != nil {
+		return res
+	}
+	var tables []soup.Root
+	if class == "" {
+		tables = doc.FindAll("table")
+	} else {
+		tables = doc.FindAll("table", "class", class)
+	}
+	for _, t := range tables {
+		res = append(res, parseTable(t))
+	}
+	return res
+}
+
+// A cell covering the next rows of a column
+type pendingCell struct {
+	text      string
+	remaining int
+}
+
+func parseTable(t soup.Root) Table {
+	table := Table{Class: t.Attrs()["class"], Header: [][]string{}, Rows: [][]string{}}
+	pending := map[int]*pendingCell{}
+	grid := [][]string{}
+	headerRows := 0
+	inHeader := true
+	for _, tr := range t.FindAll("tr") {
+		if closestTable(tr.Pointer) != t.Pointer {
+			continue
+		}
+		row := []string{}
+		fill := func() {
+			for p, ok := pending[len(row)]; ok; p, ok = pending[len(row)] {
+				row = append(row, p.text)
+				p.remaining--
+				if p.remaining == 0 {
+					delete(pending, len(row)-1)
+				}
+			}
+		}
+		allHeader := true
+		for c := tr.Pointer.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || (c.Data != "td" && c.Data != "th") {
+				continue
+			}
+			if c.Data == "td" {
+				allHeader = false
+			}
+			fill()
+			text := strings.ReplaceAll(nodeText(c), "\n", " ")
+			colspan := spanAttr(c, "colspan")
+			rowspan := spanAttr(c, "rowspan")
+			for i := 0; i < colspan; i++ {
+				if rowspan > 1 {
+					pending[len(row)] = &pendingCell{text: text, remaining: rowspan - 1}
+				}
+				row = append(row, text)
+			}
+		}
+		fill()
+		if len(row) == 0 {
+			continue
+		}
+		if inHeader && (allHeader || (tr.Pointer.Parent != nil && tr.Pointer.Parent.Data == "thead")) {
+			headerRows++
+		} else {
+			inHeader = false
+		}
+		grid = append(grid, row)
+	}
+
+	width := 0
+	for _, row := range grid {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	for i, row := range grid {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		if i < headerRows {
+			table.Header = append(table.Header, row)
+		} else {
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	if caption := t.Find("caption"); caption.Error == nil && closestTable(caption.Pointer) == t.Pointer {
+		table.Caption = strings.ReplaceAll(nodeText(caption.Pointer), "\n", " ")
+	}
+	return table
+}
+
+// Return the value of a colspan or rowspan attribute, 1 by default
+func spanAttr(n *html.Node, key string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(getAttr(n, key)))
+	if err != nil || v < 0 {
+		return 1
+	}
+	if key == "colspan" && (v == 0 || v > maxSpan) {
+		// Like the browsers, an out of range colspan is 1
+		return 1
+	}
+	if v == 0 || v > maxSpan {
+		// rowspan="0" spans to the end of the table
+		return maxSpan
+	}
+	return v
+}
+
+/*
+Names of the columns: the last header row, or the first row if the table has no header
+*/
+func (table Table) Columns() []string {
+	if len(table.Header) > 0 {
+		return table.Header[len(table.Header)-1]
+	}
+	if len(table.Rows) > 0 {
+		return table.Rows[0]
+	}
+	return []string{}
+}
+
+/*
+Data rows as maps of column names to values.
+Duplicated column names get a numeric suffix: "Name", "Name_2"...
+*/
+func (table Table) Records() []map[string]string {
+	columns := make([]string, len(table.Columns()))
+	seen := map[string]int{}
+	for i, c := range table.Columns() {
+		seen[c]++
+		columns[i] = c
+		if seen[c] > 1 {
+			columns[i] = c + "_" + strconv.Itoa(seen[c])
+		}
+	}
+	rows := table.Rows
+	if len(table.Header) == 0 && len(rows) > 0 {
+		rows = rows[1:]
+	}
+	res := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		record := make(map[string]string, len(columns))
+		for i, c := range columns {
+			if i < len(row) {
+				record[c] = row[i]
+			}
+		}
+		res = append(res, record)
+	}
+	return res
+}
+
+/*
+Write the table as CSV: the header rows followed by the data rows
+*/
+func (table Table) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(table.Header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(table.Rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+/*
+Return the table as a CSV string
+*/
+func (table Table) CSV() (string, error) {
+	var buf bytes.Buffer
+	err := table.WriteCSV(&buf)
+	return buf.String(), err
+}
+
+/*
+Return the table as JSON: its caption, header rows and data rows
+*/
+func (table Table) JSON() ([]byte, error) {
+	return json.Marshal(table)
+}
@@ -0,0 +1,58 @@
+package page
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Namespace of the templates
+const TemplateNamespace = 10
+
+/*
+List of the templates and modules transcluded in the page, including the indirect ones.
+
+Keyword arguments:
+
+* namespaces: Only keep the pages of these namespaces, ex: page.TemplateNamespace. Empty for all
+
+Return:
+
+* List of titles, with their namespace prefix
+
+* Error
+*/
+func (page *WikipediaPage) GetTemplates(namespaces ...int) ([]string, error) {
+	args := page.languageArgs(map[string]string{
+		"action":  "query",
+		"prop":    "templates",
+		"tllimit": "max",
+		"titles":  page.Title,
+	})
+	if len(namespaces) > 0 {
+		args["tlnamespace"] = JoinNamespaces(namespaces)
+	}
+	result := []string{}
+	err := utils.ContinueQuery(args, func(res models.RequestResult) bool {
+		for _, p := range res.Query.Page {
+			for _, t := range p.Templates {
+				result = append(result, t.Title)
+			}
+		}
+		return true
+	})
+	return result, err
+}
+
+/*
+Join namespace numbers with "|" for the namespace parameters of the API
+*/
+func JoinNamespaces(namespaces []int) string {
+	ns := make([]string, len(namespaces))
+	for i, v := range namespaces {
+		ns[i] = strconv.Itoa(v)
+	}
+	return strings.Join(ns, "|")
+}
@@ -0,0 +1,53 @@
+package page
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/trietmn/go-wiki/wikidata"
+)
+
+/*
+Get the ID of the Wikidata item of the page, ex: "Q7259". Save it into the page.WikidataID for later use
+
+Returns an error if the page is not linked to an item
+*/
+func (page *WikipediaPage) GetWikidataID() (string, error) {
+	if page.WikidataID != "" {
+		return page.WikidataID, nil
+	}
+	args := map[string]string{
+		"action": "query",
+		"prop":   "pageprops",
+		"ppprop": "wikibase_item",
+		"titles": page.Title,
+	}
+	res, err := page.request(args)
+	if err != nil {
+		return "", err
+	}
+	if res.Error.Code != "" {
+		return "", errors.New(res.Error.Info)
+	}
+	id := res.Query.Page[strconv.Itoa(page.PageID)].PageProps["wikibase_item"]
+	if id == "" {
+		return "", errors.New("the page has no wikidata item")
+	}
+	page.WikidataID = id
+	return page.WikidataID, nil
+}
+
+/*
+Get the Wikidata item of the page from wikidata.APIURL.
+
+Keyword arguments:
+
+* languages: Only keep the labels, descriptions and aliases of these languages. Empty for all
+*/
+func (page *WikipediaPage) GetWikidataEntity(languages ...string) (wikidata.Entity, error) {
+	id, err := page.GetWikidataID()
+	if err != nil {
+		return wikidata.Entity{}, err
+	}
+	return wikidata.GetEntity(id, languages...)
+}
@@ -0,0 +1,221 @@
+package gowiki
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/utils"
+)
+
+var (
+	// Base URL of the Wikimedia metrics REST API. Point it to a local server in tests
+	MetricsURL string = "https://wikimedia.org/api/rest_v1/metrics"
+)
+
+// Granularities of the pageview time series
+const (
+	GranularityHourly  = "hourly"
+	GranularityDaily   = "daily"
+	GranularityMonthly = "monthly"
+)
+
+// Access methods of the pageviews
+const (
+	AccessAll       = "all-access"
+	AccessDesktop   = "desktop"
+	AccessMobileApp = "mobile-app"
+	AccessMobileWeb = "mobile-web"
+)
+
+// Agent types of the pageviews
+const (
+	AgentAll       = "all-agents"
+	AgentUser      = "user"
+	AgentSpider    = "spider"
+	AgentAutomated = "automated"
+)
+
+// Number of views of a page or a project during a period
+type Pageview struct {
+	Timestamp time.Time `json:"timestamp"` // Start of the period
+	Views     int       `json:"views"`
+}
+
+// An article of the most viewed articles of a day
+type TopArticle struct {
+	Title string `json:"title"`
+	Views int    `json:"views"`
+	Rank  int    `json:"rank"`
+}
+
+// Format of the timestamps of the metrics API
+const metricsTimeFormat = "2006010215"
+
+// Return the project of the current language, ex: "en.wikipedia"
+func currentProject() string {
+	return utils.WikiLanguage + ".wikipedia"
+}
+
+// Request an endpoint of the metrics API and decode its items
+func requestMetrics(path []string, target interface{}) error {
+	for i, p := range path {
+		path[i] = url.PathEscape(p)
+	}
+	return utils.RequestJSON(MetricsURL+"/"+strings.Join(path, "/"), nil, target)
+}
+
+func defaultString(s string, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Time series returned by the metrics API
+type pageviewSeries struct {
+	Items []struct {
+		Timestamp string `json:"timestamp"`
+		Views     int    `json:"views"`
+	} `json:"items"`
+}
+
+// Turn the items of a time series into pageviews
+func (series pageviewSeries) pageviews() []Pageview {
+	result := make([]Pageview, 0, len(series.Items))
+	for _, item := range series.Items {
+		t, _ := time.Parse(metricsTimeFormat, item.Timestamp)
+		result = append(result, Pageview{Timestamp: t, Views: item.Views})
+	}
+	return result
+}
+
+/*
+Get the number of views of an article in the current language.
+
+Keyword arguments:
+
+* title: The title of the article
+
+* from, to: The first and the last day of the time series, both included
+
+* granularity: GranularityDaily or GranularityMonthly. Use "" for GranularityDaily
+
+* access: AccessAll, AccessDesktop, AccessMobileApp or AccessMobileWeb. Use "" for AccessAll
+
+* agent: AgentAll, AgentUser, AgentSpider or AgentAutomated. Use "" for AgentUser
+
+Return:
+
+* The time series of the views
+
+* Error
+*/
+func GetPageviews(title string, from time.Time, to time.Time, granularity string, access string, agent string) ([]Pageview, error) {
+	if title == "" {
+		return []Pageview{}, errors.New("the title is empty")
+	}
+	res := pageviewSeries{}
+	err := requestMetrics([]string{
+		"pageviews", "per-article", currentProject(),
+		defaultString(access, AccessAll),
+		defaultString(agent, AgentUser),
+		strings.ReplaceAll(title, " ", "_"),
+		defaultString(granularity, GranularityDaily),
+		from.Format("20060102"), to.Format("20060102"),
+	}, &res)
+	if err != nil {
+		return []Pageview{}, err
+	}
+	return res.pageviews(), nil
+}
+
+/*
+Get the number of views of a whole project.
+
+Keyword arguments:
+
+* project: The project, ex: "de.wikipedia" or "commons.wikimedia". Use "" for the Wikipedia of the current language
+
+* from, to: The first and the last day of the time series, both included
+
+* granularity: GranularityHourly, GranularityDaily or GranularityMonthly. Use "" for GranularityDaily
+
+* access: AccessAll, AccessDesktop, AccessMobileApp or AccessMobileWeb. Use "" for AccessAll
+
+* agent: AgentAll, AgentUser, AgentSpider or AgentAutomated. Use "" for AgentUser
+
+Return:
+
+* The time series of the views
+
+* Error
+*/
+func GetProjectPageviews(project string, from time.Time, to time.Time, granularity string, access string, agent string) ([]Pageview, error) {
+	res := pageviewSeries{}
+	granularity = defaultString(granularity, GranularityDaily)
+	format := "2006010200"
+	if granularity == GranularityHourly {
+		format = metricsTimeFormat
+	}
+	err := requestMetrics([]string{
+		"pageviews", "aggregate", defaultString(project, currentProject()),
+		defaultString(access, AccessAll),
+		defaultString(agent, AgentUser),
+		granularity,
+		from.Format(format), to.Format(format),
+	}, &res)
+	if err != nil {
+		return []Pageview{}, err
+	}
+	return res.pageviews(), nil
+}
+
+/*
+Get the most viewed articles of a day in the current language, at most 1000 of them.
+
+Keyword arguments:
+
+* day: The day
+
+* access: AccessAll, AccessDesktop, AccessMobileApp or AccessMobileWeb. Use "" for AccessAll
+
+Return:
+
+* The articles, ordered by rank
+
+* Error
+*/
+func GetTopPageviews(day time.Time, access string) ([]TopArticle, error) {
+	res := struct {
+		Items []struct {
+			Articles []struct {
+				Article string `json:"article"`
+				Views   int    `json:"views"`
+				Rank    int    `json:"rank"`
+			} `json:"articles"`
+		} `json:"items"`
+	}{}
+	err := requestMetrics([]string{
+		"pageviews", "top", currentProject(),
+		defaultString(access, AccessAll),
+		day.Format("2006"), day.Format("01"), day.Format("02"),
+	}, &res)
+	if err != nil {
+		return []TopArticle{}, err
+	}
+	if len(res.Items) == 0 {
+		return []TopArticle{}, fmt.Errorf("no data for %v", day.Format("2006-01-02"))
+	}
+	result := make([]TopArticle, 0, len(res.Items[0].Articles))
+	for _, a := range res.Items[0].Articles {
+		result = append(result, TopArticle{
+			Title: strings.ReplaceAll(a.Article, "_", " "),
+			Views: a.Views,
+			Rank:  a.Rank,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,348 @@
+package gowiki
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Types of recent changes
+const (
+	ChangeEdit       = "edit"
+	ChangeNew        = "new"
+	ChangeLog        = "log"
+	ChangeCategorize = "categorize"
+	ChangeExternal   = "external"
+)
+
+// Filter on a flag of the changes
+type FlagFilter int
+
+const (
+	FlagAny     FlagFilter = iota // Keep every change
+	FlagOnly                      // Only keep the changes with the flag
+	FlagExclude                   // Drop the changes with the flag
+)
+
+// Default time between 2 polls of a RecentChangesStream
+const DefaultPollInterval = 10 * time.Second
+
+// Filters and starting point of a RecentChangesStream
+type RecentChangesOptions struct {
+	Namespaces   []int      // Empty for all
+	Types        []string   // Any of ChangeEdit, ChangeNew, ChangeLog, ChangeCategorize and ChangeExternal. Empty for all
+	Bot          FlagFilter // Changes made by bots
+	Minor        FlagFilter // Changes marked as minor
+	Anon         FlagFilter // Changes made by anonymous users
+	Tag          string     // Only keep the changes with this tag, ex: "mobile edit"
+	Titles       []string   // Only keep the changes of these pages. Empty for all
+	Checkpoint   Checkpoint // Where to start. The zero checkpoint starts now
+	PollInterval time.Duration
+}
+
+// Position of a RecentChangesStream. Save it to resume the stream later
+type Checkpoint struct {
+	Continue  string    `json:"rccontinue"` // Continuation of an unfinished poll
+	Timestamp time.Time `json:"timestamp"`  // Timestamp of the last change
+	RCID      int       `json:"rcid"`       // ID of the last change
+}
+
+// A change of a page
+type RecentChange struct {
+	RCID      int       `json:"rcid"`
+	Type      string    `json:"type"`
+	Ns        int       `json:"ns"`
+	Title     string    `json:"title"`
+	PageID    int       `json:"pageid"`
+	RevID     int       `json:"revid"`
+	OldRevID  int       `json:"old_revid"`
+	User      string    `json:"user"`
+	Comment   string    `json:"comment"`
+	OldLen    int       `json:"oldlen"`
+	NewLen    int       `json:"newlen"`
+	SizeDelta int       `json:"sizedelta"` // NewLen - OldLen
+	Timestamp time.Time `json:"timestamp"`
+	Bot       bool      `json:"bot"`
+	Minor     bool      `json:"minor"`
+	Anon      bool      `json:"anon"`
+	Tags      []string  `json:"tags"`
+	LogType   string    `json:"logtype"`   // Only for ChangeLog
+	LogAction string    `json:"logaction"` // Only for ChangeLog
+}
+
+// Poll list=recentchanges for new changes, from the oldest to the newest
+type RecentChangesStream struct {
+	opts       RecentChangesOptions
+	checkpoint Checkpoint // Position after the last change fetched
+	returned   Checkpoint // Position after the last change returned by Next, or received from Changes
+	pending    int        // Changes taken from the buffer, not received yet
+	titles     map[string]bool
+	buffer     []RecentChange
+	err        error
+	lock       sync.Mutex
+}
+
+/*
+Make a stream of the recent changes. Use Next to iterate over the changes, or Changes to get them on a channel
+*/
+func NewRecentChangesStream(opts RecentChangesOptions) *RecentChangesStream {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	stream := &RecentChangesStream{opts: opts, checkpoint: opts.Checkpoint}
+	if stream.checkpoint.Continue == "" && stream.checkpoint.Timestamp.IsZero() {
+		stream.checkpoint.Timestamp = time.Now().UTC()
+	}
+	stream.returned = stream.checkpoint
+	if len(opts.Titles) > 1 {
+		stream.titles = map[string]bool{}
+		for _, t := range opts.Titles {
+			stream.titles[strings.ReplaceAll(t, "_", " ")] = true
+		}
+	}
+	return stream
+}
+
+/*
+Return the position of the stream after the last change returned. Save it once the changes
+returned so far are processed, to resume the stream later
+*/
+func (stream *RecentChangesStream) Checkpoint() Checkpoint {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	if len(stream.buffer) > 0 || stream.pending > 0 {
+		return stream.returned
+	}
+	return stream.checkpoint
+}
+
+/*
+Return the error that closed the channel of Changes, if any
+*/
+func (stream *RecentChangesStream) Err() error {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	return stream.err
+}
+
+func flagShow(flag string, filter FlagFilter) string {
+	switch filter {
+	case FlagOnly:
+		return flag
+	case FlagExclude:
+		return "!" + flag
+	}
+	return ""
+}
+
+func (stream *RecentChangesStream) args() map[string]string {
+	args := map[string]string{
+		"action":         "query",
+		"list":           "recentchanges",
+		"rcprop":         "title|ids|sizes|flags|user|comment|timestamp|tags|loginfo",
+		"rcdir":          "newer",
+		"rclimit":        "max",
+		utils.NoCacheArg: "",
+	}
+	opts := stream.opts
+	if len(opts.Namespaces) > 0 {
+		args["rcnamespace"] = page.JoinNamespaces(opts.Namespaces)
+	}
+	if len(opts.Types) > 0 {
+		args["rctype"] = strings.Join(opts.Types, "|")
+	}
+	show := []string{}
+	for _, s := range []string{flagShow("bot", opts.Bot), flagShow("minor", opts.Minor), flagShow("anon", opts.Anon)} {
+		if s != "" {
+			show = append(show, s)
+		}
+	}
+	if len(show) > 0 {
+		args["rcshow"] = strings.Join(show, "|")
+	}
+	if opts.Tag != "" {
+		args["rctag"] = opts.Tag
+	}
+	if len(opts.Titles) == 1 {
+		args["rctitle"] = opts.Titles[0]
+	}
+	if stream.checkpoint.Continue != "" {
+		args["rccontinue"] = stream.checkpoint.Continue
+	} else {
+		args["rcstart"] = stream.checkpoint.Timestamp.UTC().Format(time.RFC3339)
+	}
+	return args
+}
+
+func makeRecentChange(v models.InnerRecentChange) RecentChange {
+	timestamp, _ := time.Parse(time.RFC3339, v.Timestamp)
+	return RecentChange{
+		RCID:      v.RCID,
+		Type:      v.Type,
+		Ns:        v.Ns,
+		Title:     v.Title,
+		PageID:    v.PageID,
+		RevID:     v.RevID,
+		OldRevID:  v.OldRevID,
+		User:      v.User,
+		Comment:   v.Comment,
+		OldLen:    v.OldLen,
+		NewLen:    v.NewLen,
+		SizeDelta: v.NewLen - v.OldLen,
+		Timestamp: timestamp,
+		Bot:       v.Bot != nil,
+		Minor:     v.Minor != nil,
+		Anon:      v.Anon != nil,
+		Tags:      v.Tags,
+		LogType:   v.LogType,
+		LogAction: v.LogAction,
+	}
+}
+
+/*
+Make one request and return the new changes. The checkpoint moves after them.
+
+Returns true if more changes are ready to be fetched without waiting
+*/
+func (stream *RecentChangesStream) Poll() ([]RecentChange, bool, error) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	res, err := utils.WikiRequester(stream.args())
+	if err != nil {
+		return []RecentChange{}, false, err
+	}
+	if res.Error.Code != "" {
+		return []RecentChange{}, false, errors.New(res.Error.Info)
+	}
+	changes := []RecentChange{}
+	for _, v := range res.Query.RecentChanges {
+		change := makeRecentChange(v)
+		// rcstart is inclusive, so the last change of the previous poll comes again
+		if change.RCID <= stream.checkpoint.RCID {
+			continue
+		}
+		stream.checkpoint.RCID = change.RCID
+		if !change.Timestamp.IsZero() {
+			stream.checkpoint.Timestamp = change.Timestamp
+		}
+		if stream.titles != nil && !stream.titles[change.Title] {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	more := false
+	if c, ok := res.Continue["rccontinue"].(string); ok && c != "" {
+		stream.checkpoint.Continue = c
+		more = true
+	} else {
+		stream.checkpoint.Continue = ""
+	}
+	return changes, more, nil
+}
+
+/*
+Return the next change, polling the API every PollInterval until there is one.
+
+Returns an error if a request fails or the context is done
+*/
+func (stream *RecentChangesStream) Next(ctx context.Context) (RecentChange, error) {
+	change, err := stream.next(ctx)
+	if err != nil {
+		return RecentChange{}, err
+	}
+	stream.received(change)
+	return change, nil
+}
+
+// Take the next change from the buffer, polling when it is empty. The checkpoint does not move
+// until the change is passed to received, or back to the buffer with unread
+func (stream *RecentChangesStream) next(ctx context.Context) (RecentChange, error) {
+	for {
+		stream.lock.Lock()
+		if len(stream.buffer) > 0 {
+			change := stream.buffer[0]
+			stream.buffer = stream.buffer[1:]
+			stream.pending++
+			stream.lock.Unlock()
+			return change, nil
+		}
+		if stream.pending == 0 {
+			// Every change is received, the filtered ones included
+			stream.returned = stream.checkpoint
+		}
+		stream.lock.Unlock()
+
+		changes, more, err := stream.Poll()
+		if err != nil {
+			return RecentChange{}, err
+		}
+		if len(changes) > 0 {
+			stream.lock.Lock()
+			stream.buffer = changes
+			stream.lock.Unlock()
+			continue
+		}
+		if more {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return RecentChange{}, ctx.Err()
+		case <-time.After(stream.opts.PollInterval):
+		}
+	}
+}
+
+// Move the checkpoint after a change taken by next
+func (stream *RecentChangesStream) received(change RecentChange) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	stream.pending--
+	stream.returned = Checkpoint{Timestamp: change.Timestamp, RCID: change.RCID}
+}
+
+// Put back a change taken by next, so it is not skipped
+func (stream *RecentChangesStream) unread(change RecentChange) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	stream.pending--
+	stream.buffer = append([]RecentChange{change}, stream.buffer...)
+}
+
+/*
+Deliver the changes on a channel until the context is done or a request fails, see Err.
+
+The stream only polls when the previous changes are received, so a slow reader is never overwhelmed
+*/
+func (stream *RecentChangesStream) Changes(ctx context.Context) <-chan RecentChange {
+	ch := make(chan RecentChange)
+	go func() {
+		defer close(ch)
+		for {
+			change, err := stream.next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					stream.lock.Lock()
+					stream.err = err
+					stream.lock.Unlock()
+				}
+				return
+			}
+			// The checkpoint only moves once the change is received
+			select {
+			case ch <- change:
+				stream.received(change)
+			case <-ctx.Done():
+				stream.unread(change)
+				return
+			}
+		}
+	}()
+	return ch
+}
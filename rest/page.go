@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/trietmn/go-wiki/page"
+)
+
+/*
+Get a WikipediaPage from the page/summary endpoint. Use lang "" for utils.WikiLanguage.
+
+The page methods keep using api.php, in the same language
+*/
+func GetPage(lang string, title string) (page.WikipediaPage, error) {
+	summary, err := GetSummary(lang, title)
+	if err != nil {
+		return page.WikipediaPage{}, err
+	}
+	if summary.Type == "disambiguation" {
+		return page.WikipediaPage{}, errors.New("the page is a disambiguation page")
+	}
+	p := page.WikipediaPage{Language: lang}
+	ApplySummary(&p, summary)
+	return p, nil
+}
+
+/*
+Copy the fields of a summary into a page
+*/
+func ApplySummary(p *page.WikipediaPage, summary Summary) {
+	p.PageID = summary.PageID
+	p.Title = summary.Title
+	if p.OriginalTitle == "" {
+		p.OriginalTitle = summary.Title
+	}
+	p.Summary = summary.Extract
+	p.URL = summary.ContentURLs.Desktop.Page
+	p.CanonicalURL = summary.ContentURLs.Desktop.Page
+	p.EditURL = summary.ContentURLs.Desktop.Edit
+	p.DisplayTitle = summary.DisplayTitle
+	p.PageLanguage = summary.Lang
+	p.PageLanguageDir = summary.Dir
+	p.WikidataID = summary.WikibaseItem
+	// The summary has the timestamp of the revision, not the touched time of the page
+	if revid, err := strconv.Atoi(summary.Revision); err == nil {
+		p.LastRevID = revid
+		p.LastRevTimestamp = summary.Timestamp
+	}
+	if summary.Coordinates != nil {
+		p.Coordinate = []float64{summary.Coordinates.Lat, summary.Coordinates.Lon}
+	}
+}
+
+/*
+Load the Parsoid HTML of the page into page.HTML
+*/
+func LoadHTML(p *page.WikipediaPage) (string, error) {
+	html, err := GetHTML(p.Language, p.Title)
+	if err != nil {
+		return "", err
+	}
+	p.HTML = html
+	return p.HTML, nil
+}
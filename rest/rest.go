@@ -0,0 +1,170 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/utils"
+)
+
+var (
+	// Base URL of the REST API. %v is replaced by the language of the request.
+	// Point it to a local server in tests
+	BaseURL string = "https://%v.wikipedia.org/api/rest_v1"
+)
+
+// Summary of a page, as returned by page/summary and page/related
+type Summary struct {
+	Type              string       `json:"type"` // "standard", "disambiguation", "mainpage" or "no-extract"
+	Title             string       `json:"title"`
+	DisplayTitle      string       `json:"displaytitle"`
+	PageID            int          `json:"pageid"`
+	Namespace         Namespace    `json:"namespace"`
+	WikibaseItem      string       `json:"wikibase_item"`
+	Lang              string       `json:"lang"`
+	Dir               string       `json:"dir"`
+	Revision          string       `json:"revision"`
+	Timestamp         time.Time    `json:"timestamp"` // Timestamp of the Revision
+	Description       string       `json:"description"`
+	DescriptionSource string       `json:"description_source"`
+	Extract           string       `json:"extract"`
+	ExtractHTML       string       `json:"extract_html"`
+	Thumbnail         *Image       `json:"thumbnail"`
+	OriginalImage     *Image       `json:"originalimage"`
+	Coordinates       *Coordinates `json:"coordinates"`
+	ContentURLs       ContentURLs  `json:"content_urls"`
+}
+
+type Namespace struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+type Image struct {
+	Source string `json:"source"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type ContentURLs struct {
+	Desktop PageURLs `json:"desktop"`
+	Mobile  PageURLs `json:"mobile"`
+}
+
+type PageURLs struct {
+	Page      string `json:"page"`
+	Revisions string `json:"revisions"`
+	Edit      string `json:"edit"`
+	Talk      string `json:"talk"`
+}
+
+// A media file of a page, as returned by page/media-list
+type MediaItem struct {
+	Title         string   `json:"title"`
+	Type          string   `json:"type"` // "image", "video" or "audio"
+	LeadImage     bool     `json:"leadImage"`
+	SectionID     int      `json:"section_id"`
+	ShowInGallery bool     `json:"showInGallery"`
+	Caption       Caption  `json:"caption"`
+	SrcSet        []Source `json:"srcset"`
+}
+
+type Caption struct {
+	HTML string `json:"html"`
+	Text string `json:"text"`
+}
+
+type Source struct {
+	Src   string `json:"src"`
+	Scale string `json:"scale"`
+}
+
+/*
+URL of a REST endpoint for a page, ex: Endpoint("en", "page/summary", "Ada Lovelace").
+Use "" for utils.WikiLanguage
+*/
+func Endpoint(lang string, path string, title string) string {
+	if lang == "" {
+		lang = utils.WikiLanguage
+	}
+	title = url.PathEscape(strings.ReplaceAll(title, " ", "_"))
+	return fmt.Sprintf(BaseURL, lang) + "/" + path + "/" + title
+}
+
+// Request an endpoint. The error bodies of the API are turned into errors by utils.StatusError.
+// The response is tagged with the title, so watchers can invalidate it
+func request(lang string, path string, title string) ([]byte, error) {
+	endpoint := Endpoint(lang, path, title)
+	body, err := utils.RequestRaw(endpoint, nil)
+	if err == nil {
+		utils.Cache.Tag(endpoint, title)
+	}
+	return body, err
+}
+
+/*
+Get the summary of a page: description, intro, thumbnail and URLs. Use lang "" for utils.WikiLanguage
+*/
+func GetSummary(lang string, title string) (Summary, error) {
+	res := Summary{}
+	body, err := request(lang, "page/summary", title)
+	if err != nil {
+		return res, err
+	}
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+/*
+Get the Parsoid HTML of a page. Use lang "" for utils.WikiLanguage
+*/
+func GetHTML(lang string, title string) (string, error) {
+	body, err := request(lang, "page/html", title)
+	return string(body), err
+}
+
+/*
+Get the HTML of a page optimized for mobile devices. Use lang "" for utils.WikiLanguage
+*/
+func GetMobileHTML(lang string, title string) (string, error) {
+	body, err := request(lang, "page/mobile-html", title)
+	return string(body), err
+}
+
+/*
+Get the summaries of the pages related to a page. Use lang "" for utils.WikiLanguage
+*/
+func GetRelated(lang string, title string) ([]Summary, error) {
+	res := struct {
+		Pages []Summary `json:"pages"`
+	}{}
+	body, err := request(lang, "page/related", title)
+	if err != nil {
+		return []Summary{}, err
+	}
+	err = json.Unmarshal(body, &res)
+	return res.Pages, err
+}
+
+/*
+Get the media files of a page, in the order they appear. Use lang "" for utils.WikiLanguage
+*/
+func GetMediaList(lang string, title string) ([]MediaItem, error) {
+	res := struct {
+		Items []MediaItem `json:"items"`
+	}{}
+	body, err := request(lang, "page/media-list", title)
+	if err != nil {
+		return []MediaItem{}, err
+	}
+	err = json.Unmarshal(body, &res)
+	return res.Items, err
+}
@@ -0,0 +1,54 @@
+package gowiki
+
+import (
+	"strings"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+/*
+Add the "Template:" prefix to a template name if it is missing
+*/
+func TemplateTitle(template string) string {
+	if strings.HasPrefix(strings.ToLower(template), "template:") {
+		return template
+	}
+	return "Template:" + template
+}
+
+/*
+Get the pages transcluding a template, directly or through other templates.
+
+Keyword arguments:
+
+* template: The template name, with or without the "Template:" prefix
+
+* namespaces: Only keep the pages of these namespaces, ex: 0 for the articles. Empty for all
+
+Return:
+
+* List of titles of the pages using the template
+
+* Error
+*/
+func GetTranscludedIn(template string, namespaces ...int) ([]string, error) {
+	args := map[string]string{
+		"action":  "query",
+		"list":    "embeddedin",
+		"eititle": TemplateTitle(template),
+		"eilimit": "max",
+	}
+	if len(namespaces) > 0 {
+		args["einamespace"] = page.JoinNamespaces(namespaces)
+	}
+	result := []string{}
+	err := utils.ContinueQuery(args, func(res models.RequestResult) bool {
+		for _, p := range res.Query.EmbeddedIn {
+			result = append(result, p.Title)
+		}
+		return true
+	})
+	return result, err
+}
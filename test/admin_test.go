@@ -0,0 +1,128 @@
+package test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestPageAdministration(t *testing.T) {
+	var last url.Values
+	MockAPIServer(t, func(q url.Values) interface{} {
+		if q.Get("meta") == "tokens" {
+			return map[string]interface{}{"query": map[string]interface{}{"tokens": map[string]string{"csrftoken": "+\\"}}}
+		}
+		last = q
+		switch q.Get("action") {
+		case "move":
+			if q.Get("to") == "Existing page" {
+				return map[string]interface{}{"error": map[string]string{"code": "articleexists", "info": "A page of that name already exists."}}
+			}
+			return map[string]interface{}{"move": map[string]interface{}{
+				"from": q.Get("from"), "to": q.Get("to"), "reason": q.Get("reason"), "redirectcreated": "",
+				"talkfrom": "Talk:" + q.Get("from"), "talkto": "Talk:" + q.Get("to"),
+				"subpages":      []map[string]string{{"from": q.Get("from") + "/Archive", "to": q.Get("to") + "/Archive"}},
+				"subpages-talk": []map[string]string{{"from": "Talk:" + q.Get("from") + "/1", "to": "Talk:" + q.Get("to") + "/1"}},
+			}}
+		case "delete":
+			if q.Get("title") == "Missing page" {
+				return map[string]interface{}{"error": map[string]string{"code": "missingtitle", "info": "The page you specified doesn't exist."}}
+			}
+			return map[string]interface{}{"delete": map[string]interface{}{"title": q.Get("title"), "reason": q.Get("reason"), "logid": 4321}}
+		case "undelete":
+			return map[string]interface{}{"undelete": map[string]interface{}{"title": q.Get("title"), "revisions": 12, "fileversions": 0, "reason": q.Get("reason")}}
+		case "protect":
+			if q.Get("title") == "Main Page" {
+				return map[string]interface{}{"error": map[string]string{"code": "permissiondenied", "info": "You don't have permission to change protection levels."}}
+			}
+			return map[string]interface{}{"protect": map[string]interface{}{"title": q.Get("title"), "reason": q.Get("reason"), "protections": []map[string]string{
+				{"edit": "sysop", "expiry": "2026-11-01T00:00:00Z"},
+				{"move": "sysop", "expiry": "infinite"},
+			}}}
+		case "purge":
+			if q.Get("titles") == "Missing page" {
+				return map[string]interface{}{"purge": []map[string]interface{}{{"ns": 0, "title": "Missing page", "missing": ""}}}
+			}
+			return map[string]interface{}{"purge": []map[string]interface{}{{"ns": 0, "title": q.Get("titles"), "purged": "", "linkupdate": ""}}}
+		}
+		return map[string]interface{}{}
+	})
+
+	// Purge works without a session
+	p := page.WikipediaPage{Title: "Ada Lovelace"}
+	if err := p.Purge(true); err != nil {
+		t.Errorf("%v", err)
+	}
+	if _, ok := last["forcelinkupdate"]; !ok || last.Get("titles") != "Ada Lovelace" {
+		t.Errorf("wrong purge arguments %v", last)
+	}
+	missing := page.WikipediaPage{Title: "Missing page"}
+	var missingErr *utils.MissingPageError
+	if err := missing.Purge(false); !errors.As(err, &missingErr) {
+		t.Errorf("expected a missing page error, got %v", err)
+	}
+	if _, err := p.Delete("test", false); err == nil {
+		t.Errorf("the delete works without a session")
+	}
+	activateTestSession(t)
+
+	res, err := p.Move("Augusta Ada King", "full name", page.MoveOptions{MoveTalk: true, MoveSubpages: true, NoRedirect: true})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, k := range []string{"movetalk", "movesubpages", "noredirect", "token"} {
+		if _, ok := last[k]; !ok {
+			t.Errorf("the %v argument is not sent", k)
+		}
+	}
+	if res.To != "Augusta Ada King" || res.Talk == nil || res.Talk.To != "Talk:Augusta Ada King" || len(res.Subpages) != 2 || !res.RedirectCreated {
+		t.Errorf("wrong move result %+v", res)
+	}
+	if p.Title != "Augusta Ada King" {
+		t.Errorf("the page title is not updated")
+	}
+	var exists *utils.PageExistsError
+	if _, err := p.Move("Existing page", "", page.MoveOptions{}); !errors.As(err, &exists) {
+		t.Errorf("expected a page exists error, got %v", err)
+	}
+
+	expiry := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	protections, err := p.Protect([]page.Protection{{Type: "edit", Level: "sysop", Expiry: expiry}, {Type: "move", Level: "sysop"}}, "vandalism", true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if last.Get("protections") != "edit=sysop|move=sysop" || last.Get("expiry") != "2026-11-01T00:00:00Z|infinite" {
+		t.Errorf("wrong protect arguments %v", last)
+	}
+	if _, ok := last["cascade"]; !ok {
+		t.Errorf("the cascade argument is not sent")
+	}
+	if len(protections) != 2 || !protections[0].Expiry.Equal(expiry) || !protections[1].Expiry.IsZero() || len(p.Protection) != 2 {
+		t.Errorf("wrong protections %+v", protections)
+	}
+	mainPage := page.WikipediaPage{Title: "Main Page"}
+	var permission *utils.PermissionError
+	if _, err := mainPage.Protect([]page.Protection{{Type: "edit", Level: "sysop"}}, "", false); !errors.As(err, &permission) {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+
+	logid, err := p.Delete("cleanup", true)
+	if err != nil || logid != 4321 {
+		t.Errorf("wrong delete result %v %v", logid, err)
+	}
+	if _, ok := last["deletetalk"]; !ok {
+		t.Errorf("the deletetalk argument is not sent")
+	}
+	if _, err := missing.Delete("cleanup", false); !errors.As(err, &missingErr) {
+		t.Errorf("expected a missing page error, got %v", err)
+	}
+
+	restored, err := page.Undelete("Augusta Ada King", "mistake")
+	if err != nil || restored.Revisions != 12 || restored.Title != "Augusta Ada King" {
+		t.Errorf("wrong undelete result %+v %v", restored, err)
+	}
+}
@@ -0,0 +1,298 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki/auth"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// A fake wiki with cookie sessions, bot passwords, clientlogin and OAuth tokens
+type authServer struct {
+	sessions map[string]string // Session cookie -> user, "" for anonymous
+	logins   int
+	csrf     string
+	lock     sync.Mutex
+}
+
+func (s *authServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r.ParseForm()
+	user := ""
+	if r.Header.Get("Authorization") == "Bearer oauth-token" {
+		user = "OAuthUser"
+	}
+	cookie, err := r.Cookie("wikisession")
+	known := false
+	if err == nil && user == "" {
+		user, known = s.sessions[cookie.Value]
+	}
+	if !known {
+		id := "s" + strconv.Itoa(len(s.sessions)+1)
+		s.sessions[id] = ""
+		http.SetCookie(w, &http.Cookie{Name: "wikisession", Value: id, Path: "/"})
+		cookie = &http.Cookie{Value: id}
+	}
+	var res interface{}
+	if r.Method != "POST" {
+		res = map[string]interface{}{"error": map[string]string{"code": "mustpostparams", "info": "post only"}}
+	} else if r.Form.Get("assert") == "user" && user == "" {
+		res = map[string]interface{}{"error": map[string]string{"code": "assertuserfailed", "info": "not logged in"}}
+	} else {
+		switch r.Form.Get("action") {
+		case "query":
+			if r.Form.Get("meta") == "userinfo" {
+				id := 0
+				if user != "" {
+					id = 7
+				}
+				res = map[string]interface{}{"query": map[string]interface{}{"userinfo": map[string]interface{}{"id": id, "name": user}}}
+			} else {
+				token := "+\\"
+				if r.Form.Get("type") == "login" {
+					token = "login-" + cookie.Value + "+\\"
+				} else if user != "" {
+					token = s.csrf
+				}
+				res = map[string]interface{}{"query": map[string]interface{}{"tokens": map[string]string{r.Form.Get("type") + "token": token}}}
+			}
+		case "login", "clientlogin":
+			name, password, token := r.Form.Get("lgname"), r.Form.Get("lgpassword"), r.Form.Get("lgtoken")
+			if r.Form.Get("action") == "clientlogin" {
+				name, password, token = r.Form.Get("username"), r.Form.Get("password"), r.Form.Get("logintoken")
+			}
+			ok := token == "login-"+cookie.Value+"+\\" && password == "secret"
+			if ok {
+				s.logins++
+				s.sessions[cookie.Value] = name
+			}
+			if r.Form.Get("action") == "login" && ok {
+				res = map[string]interface{}{"login": map[string]interface{}{"result": "Success", "lguserid": 7, "lgusername": name}}
+			} else if r.Form.Get("action") == "login" {
+				res = map[string]interface{}{"login": map[string]interface{}{"result": "Failed", "reason": "Incorrect username or password entered."}}
+			} else if ok {
+				res = map[string]interface{}{"clientlogin": map[string]interface{}{"status": "PASS", "username": name}}
+			} else {
+				res = map[string]interface{}{"clientlogin": map[string]interface{}{"status": "FAIL", "message": "Incorrect password."}}
+			}
+		case "edit":
+			if r.Form.Get("token") != s.csrf {
+				res = map[string]interface{}{"error": map[string]string{"code": "badtoken", "info": "Invalid CSRF token."}}
+			} else {
+				res = map[string]interface{}{"edit": map[string]interface{}{"result": "Success", "user": user}}
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func TestSessionLogin(t *testing.T) {
+	server := &authServer{sessions: map[string]string{}, csrf: "csrf1+\\"}
+	MockHTTPServer(t, server.handle)
+	cookiePath := filepath.Join(t.TempDir(), "cookies.json")
+	session, err := auth.NewSession(cookiePath)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var loginErr *auth.LoginError
+	if err := session.Login("Example@Bot", "wrong"); !errors.As(err, &loginErr) || loginErr.Result != "Failed" {
+		t.Errorf("a wrong password does not return a LoginError: %v", err)
+	}
+	if err := session.Login("Example@Bot", "secret"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if session.User() != "Example@Bot" {
+		t.Errorf("wrong user %v", session.User())
+	}
+	token, err := session.Token(auth.TokenCSRF)
+	if err != nil || token != "csrf1+\\" {
+		t.Errorf("wrong csrf token %v, %v", token, err)
+	}
+
+	// The token has changed: the request is sent again with a new token
+	server.csrf = "csrf2+\\"
+	res, err := session.PostWithToken(auth.TokenCSRF, map[string]string{"action": "edit"})
+	if err != nil || res.Error.Code != "" {
+		t.Errorf("the token is not refreshed: %v %v", err, res.Error.Info)
+	}
+
+	// The session has expired on the server: the session logs in again
+	for id := range server.sessions {
+		server.sessions[id] = ""
+	}
+	res, err = session.PostWithToken(auth.TokenCSRF, map[string]string{"action": "edit"})
+	if err != nil || res.Error.Code != "" {
+		t.Errorf("the session does not log in again: %v %v", err, res.Error.Info)
+	}
+	if server.logins != 2 {
+		t.Errorf("expected 2 logins, got %v", server.logins)
+	}
+
+	// The saved cookies keep the session
+	if err := session.Save(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	restored, err := auth.NewSession(cookiePath)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	info, err := restored.UserInfo()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if info.ID == 0 || restored.User() != "Example@Bot" {
+		t.Errorf("the cookies are not restored: %+v", info)
+	}
+	res, err = restored.PostWithToken(auth.TokenCSRF, map[string]string{"action": "edit"})
+	if err != nil || res.Error.Code != "" {
+		t.Errorf("the restored session cannot edit: %v %v", err, res.Error.Info)
+	}
+
+	// The saved cookies have expired: the anonymous token is refused, nothing is sent anonymously
+	for id := range server.sessions {
+		server.sessions[id] = ""
+	}
+	expired, err := auth.NewSession(cookiePath)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := expired.PostWithToken(auth.TokenCSRF, map[string]string{"action": "edit"}); !errors.Is(err, auth.ErrSessionExpired) {
+		t.Errorf("got %v, expect ErrSessionExpired", err)
+	}
+}
+
+func TestSessionClientLogin(t *testing.T) {
+	server := &authServer{sessions: map[string]string{}, csrf: "csrf1+\\"}
+	MockHTTPServer(t, server.handle)
+	session, _ := auth.NewSession("")
+
+	var loginErr *auth.LoginError
+	if err := session.ClientLogin("Example", "wrong"); !errors.As(err, &loginErr) || loginErr.Result != "FAIL" {
+		t.Errorf("a wrong password does not return a LoginError: %v", err)
+	}
+	if err := session.ClientLogin("Example", "secret"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if session.User() != "Example" {
+		t.Errorf("wrong user %v", session.User())
+	}
+}
+
+func TestSessionBearerToken(t *testing.T) {
+	server := &authServer{sessions: map[string]string{}, csrf: "csrf1+\\"}
+	MockHTTPServer(t, server.handle)
+	oldClient := utils.HTTPClient
+	session, _ := auth.NewSession("")
+	session.SetBearerToken("oauth-token")
+	session.Activate()
+	defer func() {
+		session.Deactivate()
+		if auth.Default != nil || utils.HTTPClient != oldClient {
+			t.Errorf("the previous client is not restored")
+		}
+	}()
+	if auth.Default != session || utils.HTTPClient != session.Client {
+		t.Errorf("the session is not activated")
+	}
+	info, err := session.UserInfo()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if info.Name != "OAuthUser" || session.User() != "OAuthUser" {
+		t.Errorf("the bearer token is not sent: %+v", info)
+	}
+	res, err := session.PostWithToken(auth.TokenCSRF, map[string]string{"action": "edit"})
+	if err != nil || res.Error.Code != "" {
+		t.Errorf("%v %v", err, res.Error.Info)
+	}
+}
+
+func TestJarAttributes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	api, _ := url.Parse("https://en.wikipedia.org/w/api.php")
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	jar := auth.NewJar()
+	jar.SetCookies(api, []*http.Cookie{
+		{Name: "enwikiSession", Value: "s1", Secure: true, HttpOnly: true},
+		{Name: "centralauth_User", Value: "Example", Domain: ".wikipedia.org", Path: "/", Expires: expires, Secure: true},
+		{Name: "GeoIP", Value: "FR", Path: "/", MaxAge: 3600},
+		{Name: "Old", Value: "x", Path: "/", Expires: time.Now().Add(-time.Hour)},
+	})
+	if err := jar.Save(path); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	saved := map[string][]map[string]interface{}{}
+	json.Unmarshal(data, &saved)
+	cookies := map[string]map[string]interface{}{}
+	for _, c := range saved["https://en.wikipedia.org/"] {
+		cookies[c["name"].(string)] = c
+	}
+	if len(cookies) != 3 || cookies["Old"] != nil {
+		t.Errorf("wrong saved cookies %v", saved)
+	}
+	session := cookies["enwikiSession"]
+	if session["path"] != "/w" || session["domain"] != nil || session["secure"] != true || session["httponly"] != true || session["expires"] != "0001-01-01T00:00:00Z" {
+		t.Errorf("wrong session cookie %v", session)
+	}
+	central := cookies["centralauth_User"]
+	if central["domain"] != "wikipedia.org" || central["expires"] != expires.Format(time.RFC3339) {
+		t.Errorf("wrong domain cookie %v", central)
+	}
+	if cookies["GeoIP"]["expires"] == "0001-01-01T00:00:00Z" {
+		t.Errorf("the max age is not saved as an expiry %v", cookies["GeoIP"])
+	}
+
+	restored, err := auth.LoadJar(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	names := func(u string) []string {
+		parsed, _ := url.Parse(u)
+		res := []string{}
+		for _, c := range restored.Cookies(parsed) {
+			res = append(res, c.Name)
+		}
+		sort.Strings(res)
+		return res
+	}
+	// The domain cookie is sent to the other wikis, the session cookie only to its path, and none without TLS
+	if got := names("https://de.wikipedia.org/w/api.php"); !utils.CompareSlice(got, []string{"centralauth_User"}) {
+		t.Errorf("wrong cookies of the other wiki %v", got)
+	}
+	if got := names("https://en.wikipedia.org/w/api.php"); !utils.CompareSlice(got, []string{"GeoIP", "centralauth_User", "enwikiSession"}) {
+		t.Errorf("wrong cookies of the wiki %v", got)
+	}
+	if got := names("https://en.wikipedia.org/wiki/Ada_Lovelace"); !utils.CompareSlice(got, []string{"GeoIP", "centralauth_User"}) {
+		t.Errorf("wrong cookies outside of the path %v", got)
+	}
+	if got := names("http://en.wikipedia.org/w/api.php"); !utils.CompareSlice(got, []string{"GeoIP"}) {
+		t.Errorf("wrong cookies without TLS %v", got)
+	}
+
+	// The cookies expired since the save are skipped, the jars without the attributes still load
+	ioutil.WriteFile(path, []byte(`{"https://en.wikipedia.org/": [
+		{"name": "enwikiSession", "value": "s1"},
+		{"name": "centralauth_Token", "value": "t1", "domain": "wikipedia.org", "path": "/", "expires": "2001-01-01T00:00:00Z"}
+	]}`), 0600)
+	restored, err = auth.LoadJar(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got := names("https://en.wikipedia.org/wiki/Ada_Lovelace"); !utils.CompareSlice(got, []string{"enwikiSession"}) {
+		t.Errorf("wrong cookies of an old jar %v", got)
+	}
+}
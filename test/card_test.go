@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestGetCard(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	card, err := p.GetCard(240)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if card.Description != "English mathematician (1815–1852)" || card.URL != "https://en.wikipedia.org/wiki/Ada_Lovelace" || card.Image != "Ada_Lovelace_portrait.jpg" {
+		t.Errorf("unexpected card %+v", card)
+	}
+	if card.ThumbWidth != 192 || card.ThumbHeight != 240 || card.ThumbURL == "" {
+		t.Errorf("unexpected thumbnail %+v", card)
+	}
+	if card.Extract != "Augusta Ada King, Countess of Lovelace was an English mathematician and writer." {
+		t.Errorf("unexpected extract %v", card.Extract)
+	}
+}
+
+func TestGetCardFallbacks(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Celtuce (card)", PageID: 1868109}
+	card, err := p.GetCard(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if card.Description != "Variety of lettuce" || card.DescriptionSource != "local" {
+		t.Errorf("the description is not read from the page props %+v", card)
+	}
+	if card.URL != "http://en.wikipedia.org/wiki/Celtuce_(card)" || card.ThumbURL != "" {
+		t.Errorf("unexpected card %+v", card)
+	}
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+type mockMember struct {
+	title string
+	ns    int
+	kind  string
+}
+
+var mockCategories = map[string][]mockMember{
+	"Category:Analytical engines": {
+		{"Analytical Engine", 0, "page"},
+		{"Category:Charles Babbage", 14, "subcat"},
+		{"Difference engine", 0, "page"},
+		{"File:Engine.jpg", 6, "file"},
+	},
+	"Category:Charles Babbage": {
+		{"Charles Babbage", 0, "page"},
+		{"Analytical Engine", 0, "page"},
+		{"Category:Analytical engines", 14, "subcat"},
+		{"Category:Babbage family", 14, "subcat"},
+	},
+	"Category:Babbage family": {
+		{"Benjamin Herschel Babbage", 0, "page"},
+	},
+}
+
+// Serve list=categorymembers from mockCategories, 2 members per request
+func categoryHandler(listed *[]string) func(q url.Values) interface{} {
+	return func(q url.Values) interface{} {
+		title := q.Get("cmtitle")
+		if q.Get("cmcontinue") == "" {
+			*listed = append(*listed, title)
+		}
+		members := []map[string]interface{}{}
+		for _, m := range mockCategories[title] {
+			// Like MediaWiki, cmtype is ignored when sorting by timestamp
+			if q.Get("cmtype") != "" && q.Get("cmsort") != "timestamp" && !strings.Contains(q.Get("cmtype"), m.kind) {
+				continue
+			}
+			members = append(members, map[string]interface{}{
+				"pageid": len(m.title), "ns": m.ns, "title": m.title, "type": m.kind,
+				"sortkeyprefix": "", "timestamp": "2024-05-01T10:00:00Z",
+			})
+		}
+		start := 0
+		if q.Get("cmcontinue") == "page|2" {
+			start = 2
+		}
+		end := start + 2
+		res := map[string]interface{}{}
+		if end < len(members) {
+			res["continue"] = map[string]string{"cmcontinue": "page|2", "continue": "-||"}
+		} else {
+			end = len(members)
+		}
+		res["query"] = map[string]interface{}{"categorymembers": members[start:end]}
+		return res
+	}
+}
+
+func TestGetCategoryMembers(t *testing.T) {
+	listed := []string{}
+	MockAPIServer(t, categoryHandler(&listed))
+	members, err := gowiki.GetCategoryMembers("Analytical engines", gowiki.CategoryMembersOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(members) != 4 {
+		t.Fatalf("got %v members, expect 4", len(members))
+	}
+	if members[3].Title != "File:Engine.jpg" || members[3].Type != gowiki.CategoryMemberFile || members[3].Ns != 6 {
+		t.Errorf("unexpected member %+v", members[3])
+	}
+	if !members[0].Timestamp.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) || members[0].Category != "Category:Analytical engines" {
+		t.Errorf("unexpected member %+v", members[0])
+	}
+
+	members, err = gowiki.GetCategoryMembers("Category:Analytical engines", gowiki.CategoryMembersOptions{Types: []string{gowiki.CategoryMemberPage}, Limit: 1})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(members) != 1 || members[0].Title != "Analytical Engine" {
+		t.Errorf("unexpected members %+v", members)
+	}
+
+	members, err = gowiki.GetCategoryMembers("Analytical engines", gowiki.CategoryMembersOptions{Types: []string{gowiki.CategoryMemberFile}, Sort: "timestamp"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(members) != 1 || members[0].Type != gowiki.CategoryMemberFile {
+		t.Errorf("the types are not filtered when sorting by timestamp: %+v", members)
+	}
+}
+
+func TestWalkCategory(t *testing.T) {
+	listed := []string{}
+	MockAPIServer(t, categoryHandler(&listed))
+	articles, err := gowiki.GetCategoryTreeArticles("Analytical engines", 1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	titles := []string{}
+	for _, a := range articles {
+		titles = append(titles, a.Title)
+	}
+	expect := "Analytical Engine|Difference engine|Charles Babbage"
+	if strings.Join(titles, "|") != expect {
+		t.Errorf("got %v, expect %v", strings.Join(titles, "|"), expect)
+	}
+
+	// The cycle between the 2 first categories must not be followed
+	listed = listed[:0]
+	walker := gowiki.MakeCategoryWalker(-1, gowiki.CategoryMembersOptions{})
+	depths := map[string]int{}
+	err = walker.Walk("Analytical engines", func(member gowiki.CategoryMember, depth int) bool {
+		depths[member.Title] = depth
+		return true
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(listed) != 3 {
+		t.Errorf("got %v listed categories, expect 3: %v", len(listed), listed)
+	}
+	if depths["Benjamin Herschel Babbage"] != 2 || depths["Charles Babbage"] != 1 || depths["Analytical Engine"] != 0 {
+		t.Errorf("unexpected depths %v", depths)
+	}
+	if !walker.Visited["Category:Babbage family"] {
+		t.Errorf("the visited set is not updated")
+	}
+}
+
+func TestPageCategories(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	categories, err := p.GetCategories(page.CategoryOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(categories) != 3 {
+		t.Fatalf("got %v categories, expect 3", len(categories))
+	}
+	if !categories[0].Hidden || categories[1].Hidden {
+		t.Errorf("unexpected hidden flags %+v", categories)
+	}
+	c := categories[1]
+	if c.Name != "English women mathematicians" || c.SortKeyPrefix != "Lovelace, Ada" || !c.Timestamp.Equal(time.Date(2019, 11, 20, 8, 15, 0, 0, time.UTC)) {
+		t.Errorf("unexpected category %+v", c)
+	}
+	if c.Info != nil {
+		t.Errorf("the info is only set on demand")
+	}
+}
+
+func TestPageCategoriesInfo(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	categories, err := p.GetCategories(page.CategoryOptions{ExcludeHidden: true, WithInfo: true})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(categories) != 2 {
+		t.Fatalf("got %v categories, expect 2", len(categories))
+	}
+	info := categories[0].Info
+	if info == nil || info.Pages != 205 || info.Subcats != 7 || info.Size != 212 {
+		t.Errorf("unexpected info %+v", info)
+	}
+	// The category page does not exist, but the category has members
+	info = categories[1].Info
+	if info == nil || info.Files != 2 || info.Pages != 470 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
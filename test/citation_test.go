@@ -0,0 +1,92 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+const citationWikitext = `'''Ada Lovelace''' was an English mathematician.<ref name="bbc">{{cite web |last1=Smith |first1=John |last2=Doe |first2=Jane |title=Ada Lovelace: the first programmer |url=https://www.bbc.co.uk/ada |website=BBC News |date=10 December 2015 |access-date=2020-01-01 |archive-url=https://web.archive.org/ada}}</ref> She wrote the first algorithm.<ref>[https://example.org/notes The Notes], 1843.</ref>
+
+== Legacy ==
+Her work is celebrated on Ada Lovelace Day.<ref name="bbc" /> A language is named after her.<ref group="note">{{cite book |author=Jean Sammet |title=Programming Languages |publisher=Prentice-Hall |year=1969 |isbn=978-0-13-729988-1 |doi=10.1000/xyz}}</ref>
+
+== Further reading ==
+* {{Citation |vauthors=Toole BA, Stein D |title=Ada, the Enchantress of Numbers |year=1992}}
+
+== References ==
+<references>
+<ref name="unused">{{cite journal |title=List defined}}</ref>
+</references>
+`
+
+func TestCitations(t *testing.T) {
+	p := page.WikipediaPage{Title: "Ada Lovelace", Wikitext: citationWikitext}
+	citations, err := p.GetCitations()
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(citations) != 5 {
+		t.Fatalf("got %v citations, expect 5", len(citations))
+	}
+
+	bbc := citations[0]
+	if bbc.Name != "bbc" || bbc.Template != "Cite web" || bbc.Title != "Ada Lovelace: the first programmer" || bbc.Work != "BBC News" {
+		t.Errorf("unexpected citation %+v", bbc)
+	}
+	if !utils.CompareSlice(bbc.Authors, []string{"Smith, John", "Doe, Jane"}) || bbc.Date != "10 December 2015" || bbc.AccessDate != "2020-01-01" || bbc.ArchiveURL != "https://web.archive.org/ada" {
+		t.Errorf("unexpected citation %+v", bbc)
+	}
+	if len(bbc.Uses) != 2 {
+		t.Fatalf("got %v uses, expect 2", len(bbc.Uses))
+	}
+	if bbc.Uses[0].Section != "" || bbc.Uses[0].Sentence != "Ada Lovelace was an English mathematician." {
+		t.Errorf("unexpected use %+v", bbc.Uses[0])
+	}
+	if bbc.Uses[1].Section != "Legacy" || bbc.Uses[1].Sentence != "Her work is celebrated on Ada Lovelace Day." {
+		t.Errorf("unexpected use %+v", bbc.Uses[1])
+	}
+
+	notes := citations[1]
+	if notes.Template != "" || notes.URL != "https://example.org/notes" || notes.Title != "The Notes" || notes.Uses[0].Sentence != "She wrote the first algorithm." {
+		t.Errorf("unexpected citation %+v", notes)
+	}
+
+	book := citations[2]
+	if book.Group != "note" || book.ISBN != "978-0-13-729988-1" || book.DOI != "10.1000/xyz" || book.Date != "1969" || book.Publisher != "Prentice-Hall" {
+		t.Errorf("unexpected citation %+v", book)
+	}
+	if !utils.CompareSlice(book.Authors, []string{"Jean Sammet"}) {
+		t.Errorf("got %v, expect Jean Sammet", book.Authors)
+	}
+
+	reading := citations[3]
+	if reading.Template != "Citation" || reading.Uses[0].Section != "Further reading" || !utils.CompareSlice(reading.Authors, []string{"Toole BA", "Stein D"}) {
+		t.Errorf("unexpected citation %+v", reading)
+	}
+
+	unused := citations[4]
+	if unused.Name != "unused" || unused.Title != "List defined" || len(unused.Uses) != 0 {
+		t.Errorf("unexpected citation %+v", unused)
+	}
+}
+
+func TestCitationsInTemplates(t *testing.T) {
+	text := "{{Infobox person\n| name = Ada Lovelace\n| birth_date = 10 December 1815<ref name=\"b\">{{cite book |title=Ada's Algorithm}}</ref>\n}}\n" +
+		"Ada was born in London.<ref name=\"b\" /> She died in 1852.{{efn|Aged 36.<ref>{{cite web |title=Obituary}}</ref>}}"
+	p := page.WikipediaPage{Title: "Ada Lovelace", Wikitext: text}
+	citations, err := p.GetCitations()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(citations) != 2 {
+		t.Fatalf("got %v citations, expect 2: %+v", len(citations), citations)
+	}
+	if b := citations[0]; b.Name != "b" || b.Title != "Ada's Algorithm" || len(b.Uses) != 2 || b.Uses[1].Sentence != "Ada was born in London." {
+		t.Errorf("unexpected citation %+v", b)
+	}
+	if c := citations[1]; c.Title != "Obituary" || len(c.Uses) != 1 || c.Uses[0].Sentence != "Aged 36." {
+		t.Errorf("unexpected citation %+v", c)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
+	"testing"
 
 	"github.com/trietmn/go-wiki/models"
 	"github.com/trietmn/go-wiki/utils"
@@ -64,7 +68,7 @@ func MakeMockRequestMap(mockrequest map[string]models.RequestResult) map[string]
 		args := strings.Split(key, ";")
 		temp := make(map[string]string)
 		for _, arg := range args {
-			kv := strings.Split(arg, ":")
+			kv := strings.SplitN(arg, ":", 2)
 			temp[kv[0]] = kv[1]
 		}
 		res[key] = temp
@@ -74,6 +78,9 @@ func MakeMockRequestMap(mockrequest map[string]models.RequestResult) map[string]
 
 // Mock the MakeWikiRequestAPI function
 func MockRequester(args map[string]string) (models.RequestResult, error) {
+	// Several keys can have the arguments, ex: a geosearch with and without a title.
+	// Use the one with the fewest other arguments, so the choice does not depend on the order of the map
+	match := ""
 OuterLoop:
 	for key, value := range MockRequestMap {
 		for k, v := range args {
@@ -87,8 +94,43 @@ OuterLoop:
 				continue OuterLoop
 			}
 		}
-		utils.Cache.Add(key, MockWikiRequest[key])
-		return MockWikiRequest[key], nil
+		if match == "" || len(value) < len(MockRequestMap[match]) || (len(value) == len(MockRequestMap[match]) && key < match) {
+			match = key
+		}
+	}
+	if match == "" {
+		return models.RequestResult{}, errors.New("mock request not exist")
 	}
-	return models.RequestResult{}, errors.New("mock request not exist")
+	utils.Cache.Add(match, MockWikiRequest[match])
+	return MockWikiRequest[match], nil
+}
+
+/*
+Serve the API requests with `handler` instead of the mock requests, for the tests that need
+the real requester, ex: to follow continuations. The handler gets the query and the form of POST requests,
+and its response is sent as JSON. The mock requester is restored at the end of the test
+*/
+func MockAPIServer(t *testing.T, handler func(q url.Values) interface{}) *httptest.Server {
+	return MockHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseMultipartForm(32 << 20)
+		json.NewEncoder(w).Encode(handler(r.Form))
+	})
+}
+
+/*
+Same as MockAPIServer, for the tests that need the whole HTTP request, ex: to check the cookies
+*/
+func MockHTTPServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(handler)
+	oldURL := utils.WikiURL
+	utils.WikiURL = server.URL + "/%v/api.php"
+	utils.WikiRequester = utils.RequestWikiApi
+	utils.Cache.Clear()
+	t.Cleanup(func() {
+		server.Close()
+		utils.WikiURL = oldURL
+		utils.WikiRequester = MockRequester
+		utils.Cache.Clear()
+	})
+	return server
 }
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func newMediaServer(files map[string][]byte, ranges *[]string, lock *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		lock.Lock()
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		lock.Unlock()
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadImages(t *testing.T) {
+	portrait := bytes.Repeat([]byte("portrait"), 1000)
+	engine := bytes.Repeat([]byte("engine"), 500)
+	var ranges []string
+	var lock sync.Mutex
+	server := newMediaServer(map[string][]byte{"/portrait.jpg": portrait, "/engine.png": engine}, &ranges, &lock)
+	defer server.Close()
+
+	dir := t.TempDir()
+	// A previous download stopped half way
+	err := ioutil.WriteFile(filepath.Join(dir, "Ada_Lovelace_portrait.jpg.part"), portrait[:3000], 0644)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	images := []page.Image{
+		{Title: "File:Ada Lovelace portrait.jpg", URL: server.URL + "/portrait.jpg", SHA1: sha1Hex(portrait), License: "Public domain"},
+		{Title: "File:Analytical engine.png", URL: server.URL + "/engine.png", SHA1: sha1Hex(engine), License: "CC BY-SA 4.0"},
+	}
+	manifest, err := page.DownloadImages(images, "Ada Lovelace", dir, page.DownloadOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "Ada_Lovelace_portrait.jpg"))
+	if err != nil || !bytes.Equal(got, portrait) {
+		t.Errorf("the resumed file is not complete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Ada_Lovelace_portrait.jpg.part")); !os.IsNotExist(err) {
+		t.Errorf("the partial file is not removed")
+	}
+	if !strings.Contains(strings.Join(ranges, ","), "bytes=3000-") {
+		t.Errorf("the download is not resumed, ranges %v", ranges)
+	}
+
+	saved, err := page.ReadManifest(filepath.Join(dir, page.DefaultManifestName))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(saved.Files) != 2 || len(manifest.Files) != 2 {
+		t.Fatalf("got %v manifest entries, expect 2", len(saved.Files))
+	}
+	if saved.Files[1].File != "Analytical_engine.png" || saved.Files[1].License != "CC BY-SA 4.0" || saved.Files[1].SourcePages[0] != "Ada Lovelace" {
+		t.Errorf("unexpected manifest entry %+v", saved.Files[1])
+	}
+
+	// Files already downloaded are not fetched again, and the manifest keeps every source page
+	ranges = nil
+	manifest, err = page.DownloadImages(images[:1], "Charles Babbage", dir, page.DownloadOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(ranges) != 0 {
+		t.Errorf("the existing file is downloaded again")
+	}
+	if len(manifest.Files) != 2 || len(manifest.Files[0].SourcePages) != 2 {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	var ranges []string
+	var lock sync.Mutex
+	server := newMediaServer(map[string][]byte{"/broken.jpg": []byte("corrupted")}, &ranges, &lock)
+	defer server.Close()
+
+	dir := t.TempDir()
+	images := []page.Image{{Title: "File:Broken.jpg", URL: server.URL + "/broken.jpg", SHA1: sha1Hex([]byte("original"))}}
+	manifest, err := page.DownloadImages(images, "Ada Lovelace", dir, page.DownloadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expect a checksum error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Broken.jpg")); !os.IsNotExist(err) {
+		t.Errorf("a corrupted file is kept")
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].Error == "" {
+		t.Errorf("the failure is not recorded in the manifest %+v", manifest)
+	}
+}
+
+func TestDownloadDuplicateImages(t *testing.T) {
+	portrait := bytes.Repeat([]byte("portrait"), 1000)
+	var ranges []string
+	var lock sync.Mutex
+	server := newMediaServer(map[string][]byte{"/portrait.jpg": portrait}, &ranges, &lock)
+	defer server.Close()
+
+	dir := t.TempDir()
+	img := page.Image{Title: "File:Ada Lovelace portrait.jpg", URL: server.URL + "/portrait.jpg", SHA1: sha1Hex(portrait)}
+	same := img
+	same.Title = "File:Ada_Lovelace_portrait.jpg"
+	manifest, err := page.DownloadImages([]page.Image{img, img, same}, "Ada Lovelace", dir, page.DownloadOptions{Concurrency: 3})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(ranges) != 1 || len(manifest.Files) != 1 {
+		t.Errorf("the file is downloaded %v times, with %v manifest entries", len(ranges), len(manifest.Files))
+	}
+	if got, err := ioutil.ReadFile(filepath.Join(dir, "Ada_Lovelace_portrait.jpg")); err != nil || !bytes.Equal(got, portrait) {
+		t.Errorf("the file is corrupted: %v", err)
+	}
+}
+
+func TestDownloadSlowFile(t *testing.T) {
+	data := bytes.Repeat([]byte("slow"), 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data[:200])
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write(data[200:])
+	}))
+	defer server.Close()
+	// The timeout of the API requests does not cut the downloads
+	old := utils.HTTPClient
+	utils.HTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+	defer func() { utils.HTTPClient = old }()
+
+	images := []page.Image{{Title: "File:Slow.jpg", URL: server.URL + "/slow.jpg", SHA1: sha1Hex(data)}}
+	if _, err := page.DownloadImages(images, "Ada Lovelace", t.TempDir(), page.DownloadOptions{}); err != nil {
+		t.Errorf("%v", err)
+	}
+}
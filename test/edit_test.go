@@ -0,0 +1,170 @@
+package test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/trietmn/go-wiki/auth"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Use an anonymous session for the write methods during the test
+func activateTestSession(t *testing.T) *auth.Session {
+	session, err := auth.NewSession("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	session.Activate()
+	t.Cleanup(session.Deactivate)
+	return session
+}
+
+func TestEditPage(t *testing.T) {
+	var edits []url.Values
+	fetches := 0
+	MockAPIServer(t, func(q url.Values) interface{} {
+		switch {
+		case q.Get("meta") == "tokens":
+			return map[string]interface{}{"query": map[string]interface{}{"tokens": map[string]string{"csrftoken": "+\\"}}}
+		case q.Get("prop") == "revisions":
+			fetches++
+			return map[string]interface{}{"query": map[string]interface{}{"pages": map[string]interface{}{
+				"974": map[string]interface{}{"pageid": 974, "title": "Ada Lovelace", "revisions": []map[string]interface{}{
+					{"revid": 500, "timestamp": "2026-10-18T08:00:00Z", "*": "'''Ada''' was a mathematician."},
+				}},
+			}}}
+		case q.Get("action") != "edit":
+			return map[string]interface{}{}
+		}
+		edits = append(edits, q)
+		switch q.Get("title") {
+		case "Protected page":
+			return map[string]interface{}{"error": map[string]string{"code": "protectedpage", "info": "This page has been protected."}}
+		case "Filtered page":
+			return map[string]interface{}{"error": map[string]interface{}{
+				"code": "abusefilter-disallowed", "info": "This action has been disallowed.",
+				"abusefilter": map[string]interface{}{"id": 12, "description": "Page blanking", "actions": []string{"disallow"}},
+			}}
+		case "Captcha page":
+			return map[string]interface{}{"edit": map[string]interface{}{"result": "Failure", "captcha": map[string]string{"type": "image"}}}
+		}
+		if q.Get("basetimestamp") != "" && q.Get("basetimestamp") != "2026-10-18T08:00:00Z" {
+			return map[string]interface{}{"error": map[string]string{"code": "editconflict", "info": "Edit conflict."}}
+		}
+		return map[string]interface{}{"edit": map[string]interface{}{
+			"result": "Success", "pageid": 974, "title": q.Get("title"),
+			"oldrevid": 500, "newrevid": 501, "newtimestamp": "2026-10-19T09:00:00Z",
+		}}
+	})
+
+	p := page.WikipediaPage{PageID: 974, Title: "Ada Lovelace"}
+	if _, err := p.Edit("text", "summary", page.EditOptions{}); err != auth.ErrNoSession {
+		t.Errorf("expected ErrNoSession, got %v", err)
+	}
+	activateTestSession(t)
+
+	if _, err := p.GetWikitext(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := p.Edit("'''Ada''' was an English mathematician.", "more precise", page.EditOptions{Minor: true, Bot: true, Watchlist: page.WatchlistWatch})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if res.NewRevID != 501 || res.OldRevID != 500 || res.New || res.NewTimestamp.IsZero() {
+		t.Errorf("wrong edit result %+v", res)
+	}
+	q := edits[0]
+	for k, v := range map[string]string{
+		"text": "'''Ada''' was an English mathematician.", "summary": "more precise", "token": "+\\", "watchlist": "watch",
+		"basetimestamp": "2026-10-18T08:00:00Z", "baserevid": "500", "minor": "", "bot": "", "nocreate": "",
+	} {
+		if _, ok := q[k]; !ok || q.Get(k) != v {
+			t.Errorf("wrong %v argument: %q", k, q.Get(k))
+		}
+	}
+	if q.Get("starttimestamp") == "" {
+		t.Errorf("the start timestamp is not sent")
+	}
+	if p.WikitextRevID != 501 || p.LastRevID != 501 || p.Wikitext != "'''Ada''' was an English mathematician." {
+		t.Errorf("the page is not updated after the edit")
+	}
+
+	// The edit base is fetched again, never from the cache
+	fresh := page.WikipediaPage{PageID: 974, Title: "Ada Lovelace"}
+	if _, err := fresh.GetWikitext(); err != nil || fetches != 2 {
+		t.Errorf("the wikitext is not fetched again: %v fetches, %v", fetches, err)
+	}
+
+	// The text is based on an old revision
+	_, err = p.Edit("new text", "", page.EditOptions{BaseTimestamp: p.WikitextTimestamp.AddDate(0, 0, -1)})
+	var conflict *utils.EditConflictError
+	var apiErr *utils.APIError
+	if !errors.As(err, &conflict) || !errors.As(err, &apiErr) || apiErr.Code != "editconflict" {
+		t.Errorf("expected an edit conflict, got %v", err)
+	}
+
+	p = page.WikipediaPage{Title: "Protected page"}
+	_, err = p.PrependText("{{Notice}}\n", "notice", page.EditOptions{})
+	var protected *utils.ProtectedPageError
+	if !errors.As(err, &protected) {
+		t.Errorf("expected a protected page error, got %v", err)
+	}
+
+	p = page.WikipediaPage{Title: "Filtered page"}
+	_, err = p.AppendSection("Talk", "Hello", "", page.EditOptions{})
+	var filter *utils.AbuseFilterError
+	if !errors.As(err, &filter) || filter.FilterID != "12" || filter.Description != "Page blanking" || len(filter.Actions) != 1 {
+		t.Errorf("expected an abuse filter error, got %#v", err)
+	}
+	q = edits[len(edits)-1]
+	if q.Get("section") != "new" || q.Get("sectiontitle") != "Talk" || q.Get("text") != "Hello" {
+		t.Errorf("wrong new section arguments %v", q)
+	}
+
+	p = page.WikipediaPage{PageID: 974, Title: "Captcha page"}
+	if _, err = p.Edit("text", "", page.EditOptions{}); !errors.As(err, &apiErr) || apiErr.Code != "captcha" {
+		t.Errorf("expected a captcha error, got %v", err)
+	}
+
+	// The text of a fresh page is not loaded, so its latest revision is loaded as the base
+	fetches = 0
+	fresh = page.WikipediaPage{PageID: 974, Title: "Ada Lovelace"}
+	if _, err := fresh.Edit("new text", "", page.EditOptions{}); err != nil {
+		t.Fatalf("%v", err)
+	}
+	q = edits[len(edits)-1]
+	if fetches != 1 || q.Get("basetimestamp") != "2026-10-18T08:00:00Z" || q.Get("baserevid") != "500" || q.Get("starttimestamp") == "" {
+		t.Errorf("the edit of a fresh page has no base: %v fetches, %v", fetches, q)
+	}
+}
+
+func TestCreatePage(t *testing.T) {
+	MockAPIServer(t, func(q url.Values) interface{} {
+		if q.Get("meta") == "tokens" {
+			return map[string]interface{}{"query": map[string]interface{}{"tokens": map[string]string{"csrftoken": "+\\"}}}
+		}
+		if q.Get("title") == "Existing page" {
+			return map[string]interface{}{"error": map[string]string{"code": "articleexists", "info": "The article you tried to create has been created already."}}
+		}
+		return map[string]interface{}{"edit": map[string]interface{}{
+			"result": "Success", "pageid": 2000, "title": q.Get("title"), "new": "",
+			"oldrevid": 0, "newrevid": 600, "newtimestamp": "2026-10-19T09:00:00Z",
+		}}
+	})
+	activateTestSession(t)
+
+	res, err := page.CreatePage("New page", "Some text", "create", page.EditOptions{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !res.New || res.PageID != 2000 || res.NewRevID != 600 {
+		t.Errorf("wrong result %+v", res)
+	}
+	_, err = page.CreatePage("Existing page", "Some text", "create", page.EditOptions{})
+	var exists *utils.PageExistsError
+	if !errors.As(err, &exists) {
+		t.Errorf("expected a page exists error, got %v", err)
+	}
+}
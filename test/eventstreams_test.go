@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki/eventstreams"
+)
+
+// JSON of a recentchange event, on a single line
+func sseRecentChange(id int, wiki string, domain string, ns int, title string) string {
+	return strings.ReplaceAll(fmt.Sprintf(`{"$schema": "/mediawiki/recentchange/1.0.0", "meta": {"uri": "https://%v/wiki/%v", "id": "e%v", "dt": "2026-10-19T10:00:0%vZ", "domain": "%v", "stream": "mediawiki.recentchange", "partition": 0, "offset": %v},
+		"id": %v, "type": "edit", "namespace": %v, "title": "%v", "comment": "fix", "timestamp": 1792404000, "user": "Example", "bot": false, "minor": true,
+		"length": {"old": 100, "new": 120}, "revision": {"old": %v, "new": %v}, "server_name": "%v", "wiki": "%v"}`,
+		domain, title, id, id, domain, id, id, ns, title, id*10, id*10+1, domain, wiki), "\n\t\t", " ")
+}
+
+func TestEventStreams(t *testing.T) {
+	var lock sync.Mutex
+	lastIDs := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/stream/recentchange,page-create" {
+			http.NotFound(w, r)
+			return
+		}
+		lock.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		connection := len(lastIDs)
+		lock.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		if connection == 1 {
+			fmt.Fprintf(w, ": ok\n\nretry: 10\n\n")
+			fmt.Fprintf(w, "event: message\nid: [{\"offset\":1}]\ndata: %v\n\n", sseRecentChange(1, "enwiki", "en.wikipedia.org", 0, "Ada Lovelace"))
+			fmt.Fprintf(w, "event: message\nid: [{\"offset\":2}]\ndata: %v\n\n", sseRecentChange(2, "dewiki", "de.wikipedia.org", 0, "Ada Lovelace"))
+			// The connection drops here
+			return
+		}
+		fmt.Fprintf(w, "event: message\nid: [{\"offset\":3}]\ndata: %v\n\n", sseRecentChange(3, "enwiki", "en.wikipedia.org", 1, "Talk:Ada Lovelace"))
+		fmt.Fprintf(w, "event: message\nid: [{\"offset\":4}]\ndata: {\"meta\": {\"stream\": \"mediawiki.page-create\", \"domain\": \"en.wikipedia.org\", \"dt\": \"2026-10-19T10:00:05Z\"},\n")
+		fmt.Fprintf(w, "data: \"database\": \"enwiki\", \"page_id\": 77, \"page_title\": \"Analytical_Engine_(replica)\", \"page_namespace\": 0, \"rev_id\": 501,\n")
+		fmt.Fprintf(w, "data: \"rev_timestamp\": \"2026-10-19T10:00:05Z\", \"performer\": {\"user_text\": \"Example\", \"user_is_bot\": false}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	old := eventstreams.BaseURL
+	eventstreams.BaseURL = server.URL + "/v2/stream"
+	defer func() { eventstreams.BaseURL = old }()
+
+	client := eventstreams.NewClient(eventstreams.Options{
+		Streams:        []string{eventstreams.StreamRecentChange, eventstreams.StreamPageCreate},
+		Wikis:          []string{"enwiki"},
+		Namespaces:     []int{0},
+		BufferSize:     1,
+		ReconnectDelay: time.Second,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := []eventstreams.Event{}
+	for event := range client.Subscribe(ctx) {
+		events = append(events, event)
+		if len(events) == 2 {
+			cancel()
+		}
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %v events, expect 2", len(events))
+	}
+	change := events[0].RecentChange
+	if change == nil || change.Title != "Ada Lovelace" || change.Revision.New != 11 || change.Length.New != 120 || !change.Minor || events[0].Meta.Domain != "en.wikipedia.org" {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+	revision := events[1].Revision
+	if revision == nil || events[1].Stream != eventstreams.StreamPageCreate || revision.PageTitle != "Analytical_Engine_(replica)" || revision.RevID != 501 || revision.Performer.UserText != "Example" {
+		t.Errorf("unexpected event %+v", events[1])
+	}
+	if len(lastIDs) != 2 || lastIDs[0] != "" || lastIDs[1] != `[{"offset":2}]` {
+		t.Errorf("unexpected Last-Event-ID headers %v", lastIDs)
+	}
+	if client.LastEventID() != `[{"offset":4}]` {
+		t.Errorf("unexpected last event ID %v", client.LastEventID())
+	}
+}
+
+func TestEventStreamsLastEventIDFollowsConsumer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 1; i <= 5; i++ {
+			fmt.Fprintf(w, "id: [{\"offset\":%v}]\ndata: %v\n\n", i, sseRecentChange(i, "enwiki", "en.wikipedia.org", 0, "Ada Lovelace"))
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	old := eventstreams.BaseURL
+	eventstreams.BaseURL = server.URL
+	defer func() { eventstreams.BaseURL = old }()
+
+	client := eventstreams.NewClient(eventstreams.Options{Streams: []string{eventstreams.StreamRecentChange}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch := client.Subscribe(ctx)
+	event := <-ch
+	// The other events are read into the buffer, but not received yet
+	time.Sleep(50 * time.Millisecond)
+	if event.ID != `[{"offset":1}]` || client.LastEventID() != event.ID {
+		t.Errorf("got last event ID %v, expect the one of the received event %v", client.LastEventID(), event.ID)
+	}
+	<-ch
+	time.Sleep(10 * time.Millisecond)
+	if client.LastEventID() != `[{"offset":2}]` {
+		t.Errorf("got last event ID %v, expect offset 2", client.LastEventID())
+	}
+}
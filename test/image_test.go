@@ -0,0 +1,158 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestImageMetadata(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	images, err := p.GetImages(page.ImageOptions{ThumbWidth: 320})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(images) != 4 {
+		t.Fatalf("got %v images, expect 4", len(images))
+	}
+	portrait := images[0]
+	if portrait.Title != "File:Ada Lovelace portrait.jpg" || portrait.Width != 2400 || portrait.Height != 3000 || portrait.Size != 1150000 || portrait.MIME != "image/jpeg" {
+		t.Errorf("unexpected image %+v", portrait)
+	}
+	if portrait.ThumbWidth != 320 || portrait.ThumbURL != "https://upload.wikimedia.org/thumb/Ada_Lovelace_portrait.jpg/320px-Ada_Lovelace_portrait.jpg" {
+		t.Errorf("unexpected thumbnail %+v", portrait)
+	}
+	if portrait.License != "Public domain" || portrait.Artist != "Alfred Edward Chalon" || portrait.Credit != "Science Museum & Library" || portrait.Description != "Portrait of Ada" {
+		t.Errorf("unexpected metadata %+v", portrait)
+	}
+	if images[1].LicenseURL != "https://creativecommons.org/licenses/by-sa/4.0" {
+		t.Errorf("unexpected license URL %v", images[1].LicenseURL)
+	}
+}
+
+func TestImageFilters(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	images, err := p.GetImages(page.ImageOptions{ThumbWidth: 320, ExcludeIcons: true})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	titles := []string{}
+	for _, img := range images {
+		titles = append(titles, img.Title)
+	}
+	if !utils.CompareSlice(titles, []string{"File:Ada Lovelace portrait.jpg", "File:Analytical engine diagram.png"}) {
+		t.Errorf("got %v", titles)
+	}
+	images, _ = p.GetImages(page.ImageOptions{ThumbWidth: 320, ExcludeSVG: true})
+	if len(images) != 2 {
+		t.Errorf("got %v images, expect 2 without SVG", len(images))
+	}
+}
+
+func TestLeadImage(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	img, err := p.GetLeadImage(200)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if img.Title != "File:Ada Lovelace portrait.jpg" || img.ThumbWidth != 200 {
+		t.Errorf("unexpected lead image %+v", img)
+	}
+}
+
+func TestLeadImageLanguage(t *testing.T) {
+	paths := map[string]string{}
+	MockHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		paths[r.Form.Get("prop")] = r.URL.Path
+		query := map[string]interface{}{"pages": map[string]interface{}{"974": map[string]interface{}{"pageid": 974, "title": "Ada Lovelace", "pageimage": "Ada_fr.jpg"}}}
+		if r.Form.Get("prop") == "imageinfo" {
+			query = map[string]interface{}{
+				"normalized": []map[string]string{{"from": "File:Ada_fr.jpg", "to": "File:Ada fr.jpg"}},
+				"pages":      map[string]interface{}{"-1": map[string]interface{}{"ns": 6, "title": "File:Ada fr.jpg", "imageinfo": []map[string]interface{}{{"url": "https://upload.example.org/fr/Ada_fr.jpg"}}}},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"query": query})
+	})
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974, Language: "fr"}
+	img, err := p.GetLeadImage(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if paths["imageinfo"] != "/fr/api.php" || img.URL != "https://upload.example.org/fr/Ada_fr.jpg" {
+		t.Errorf("the file is not looked up on the wiki of the page: %v %+v", paths, img)
+	}
+}
+
+func TestIsIconWholeWords(t *testing.T) {
+	for _, title := range []string{"File:Silicon_Valley.jpg", "File:Lexicon page.png", "File:Sparrow.jpg", "File:Harrow_School.jpg", "File:Symbolism painting.jpg"} {
+		if page.IsIcon(page.Image{Title: title, Width: 800, Height: 600}, page.DefaultIconSize) {
+			t.Errorf("%v is not an icon", title)
+		}
+	}
+	for _, title := range []string{"File:Flag_of_France.svg", "File:Red arrow icon.png", "File:Commons-logo.svg", "File:Edit-clear.svg", "File:Icons-mini-file acrobat.gif"} {
+		if !page.IsIcon(page.Image{Title: title, Width: 800, Height: 600}, page.DefaultIconSize) {
+			t.Errorf("%v is an icon", title)
+		}
+	}
+}
+
+func TestImagesURLError(t *testing.T) {
+	MockAPIServer(t, func(q url.Values) interface{} {
+		if q.Get("gimcontinue") != "" {
+			return map[string]interface{}{"error": map[string]string{"code": "internal_api_error", "info": "Database error"}}
+		}
+		return map[string]interface{}{
+			"continue": map[string]string{"gimcontinue": "974|B.jpg", "continue": "gimcontinue||"},
+			"query": map[string]interface{}{"pages": map[string]interface{}{
+				"-1": map[string]interface{}{"title": "File:A.jpg", "imageinfo": []map[string]string{{"url": "https://upload.example.org/A.jpg"}}},
+			}},
+		}
+	})
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	if urls, err := p.GetImagesURL(); err == nil || p.CheckedImage {
+		t.Errorf("got %v without the error of the second request", urls)
+	}
+}
+
+func TestImageInfoBatches(t *testing.T) {
+	requests := []url.Values{}
+	MockAPIServer(t, func(q url.Values) interface{} {
+		requests = append(requests, q)
+		pages := map[string]interface{}{}
+		normalized := []map[string]string{}
+		for i, title := range strings.Split(q.Get("titles"), "|") {
+			// The API answers with the titles normalized
+			to := strings.ReplaceAll(strings.Replace(title, "file:", "File:", 1), "_", " ")
+			if to != title {
+				normalized = append(normalized, map[string]string{"from": title, "to": to})
+			}
+			pages[strconv.Itoa(-1-i)] = map[string]interface{}{"title": to, "imageinfo": []map[string]string{{"url": "https://upload.example.org/" + to}}}
+		}
+		return map[string]interface{}{"query": map[string]interface{}{"normalized": normalized, "pages": pages}}
+	})
+	titles := []string{"file:Ada_Lovelace.jpg"}
+	for i := 1; i < 60; i++ {
+		titles = append(titles, fmt.Sprintf("File:Image %v.jpg", i))
+	}
+	images, err := page.GetImageInfo(titles, 0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(requests) != 2 || len(strings.Split(requests[0].Get("titles"), "|")) != 50 {
+		t.Errorf("got %v requests, expect 2 batches of at most 50 titles", len(requests))
+	}
+	if len(images) != 60 || images[0].Title != "File:Ada Lovelace.jpg" || images[59].Title != "File:Image 59.jpg" {
+		t.Errorf("got %v images, expect 60 in the order of the titles", len(images))
+	}
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestPageInfoFields(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p, err := gowiki.GetPage("Celtuce", -1, false, true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	touched := time.Date(2013, 8, 17, 3, 30, 23, 0, time.UTC)
+	if !p.Touched.Equal(touched) {
+		t.Errorf("got %v, expect %v", p.Touched, touched)
+	}
+	if p.LastRevID != 562756085 || p.Length != 1662 || p.ContentModel != "wikitext" || p.PageLanguage != "en" {
+		t.Errorf("unexpected info %+v", p)
+	}
+	if p.EditURL != "http://en.wikipedia.org/w/index.php?title=Celtuce&action=edit" {
+		t.Errorf("got %v", p.EditURL)
+	}
+	if p.New {
+		t.Errorf("the page is not new")
+	}
+}
+
+func TestLoadExtraInfo(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	err := p.LoadInfo(page.InfoProtection, page.InfoTalkID, page.InfoWatchers, page.InfoVisitingWatchers, page.InfoDisplayTitle, page.InfoNew)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if p.TalkID != 975 || p.Watchers != 812 || p.VisitingWatchers != 95 || p.DisplayTitle != "<span>Ada Lovelace</span>" || !p.New {
+		t.Errorf("unexpected info %+v", p)
+	}
+	if p.CanonicalURL != "https://en.wikipedia.org/wiki/Ada_Lovelace" || p.PageLanguageDir != "ltr" {
+		t.Errorf("unexpected urls %+v", p)
+	}
+	if len(p.Protection) != 2 {
+		t.Fatalf("got %v protections, expect 2", len(p.Protection))
+	}
+	if p.Protection[0].Level != "autoconfirmed" || !p.Protection[0].Expiry.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected protection %+v", p.Protection[0])
+	}
+	if p.Protection[1].Type != "move" || !p.Protection[1].Expiry.IsZero() {
+		t.Errorf("an infinite protection has no expiry, got %+v", p.Protection[1])
+	}
+}
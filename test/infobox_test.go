@@ -0,0 +1,99 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+const infoboxWikitext = `{{Short description|Capital of France}}
+{{Infobox settlement
+| name              = Paris
+| country           = [[France]]
+| population_total  = 2,102,650<ref>{{cite web|title=Populations}}</ref>
+| established_date  = {{start date|0508|5}}
+| leader_name       = {{ubl|[[Anne Hidalgo]]|[[Emmanuel Grégoire|E. Grégoire]]}}
+| image_skyline     = [[File:Paris.jpg|250px]]
+| embedded          = {{Infobox river|name=Seine|length={{convert|777|km}}}}
+}}
+'''Paris''' is the capital of [[France]].`
+
+const infoboxHTML = `<div><table class="infobox vcard"><caption>Paris</caption><tbody>
+<tr><th colspan="2" class="infobox-above">Paris</th></tr>
+<tr><th class="infobox-label">Country</th><td class="infobox-data"><a href="/wiki/France" title="France">France</a></td></tr>
+<tr><th class="infobox-label">Population</th><td class="infobox-data">2,102,650<sup class="reference"><a href="#cite_note-1">[1]</a></sup></td></tr>
+<tr><th class="infobox-label">Mayor</th><td class="infobox-data"><a href="/wiki/Anne_Hidalgo" title="Anne Hidalgo">Anne Hidalgo</a><br><a href="/w/index.php?title=X&amp;action=edit" class="new">X</a></td></tr>
+</tbody></table></div>`
+
+func TestInfoboxWikitext(t *testing.T) {
+	p := page.WikipediaPage{Title: "Paris", Wikitext: infoboxWikitext}
+	boxes, err := p.GetInfobox()
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(boxes) != 2 {
+		t.Fatalf("got %v infoboxes, expect 2", len(boxes))
+	}
+	box := boxes[0]
+	if box.Name != "Infobox settlement" || box.Title != "Paris" {
+		t.Errorf("got %v (%v), expect Infobox settlement (Paris)", box.Name, box.Title)
+	}
+	keys := []string{"name", "country", "population_total", "established_date", "leader_name", "image_skyline", "embedded"}
+	if res := box.Keys(); len(res) != len(keys) {
+		t.Errorf("got %v, expect %v", res, keys)
+	} else {
+		for i := range keys {
+			if res[i] != keys[i] {
+				t.Errorf("got %v, expect %v", res[i], keys[i])
+			}
+		}
+	}
+	expect := map[string]string{
+		"country":          "France",
+		"population_total": "2,102,650",
+		"established_date": "0508-05",
+		"leader_name":      "Anne Hidalgo, E. Grégoire",
+		"image_skyline":    "",
+	}
+	values := box.Map()
+	for k, v := range expect {
+		if values[k] != v {
+			t.Errorf("%v: got %q, expect %q", k, values[k], v)
+		}
+	}
+	leader, _ := box.Get("leader_name")
+	if !utils.CompareSlice(leader.Links, []string{"Anne Hidalgo", "Emmanuel Grégoire"}) {
+		t.Errorf("got %v", leader.Links)
+	}
+	if population, _ := box.Get("population_total"); population.Raw != "2,102,650<ref>{{cite web|title=Populations}}</ref>" {
+		t.Errorf("got %v", population.Raw)
+	}
+	if river, _ := boxes[1].Get("length"); boxes[1].Name != "Infobox river" || river.Text != "777 km" {
+		t.Errorf("got %v %v, expect Infobox river 777 km", boxes[1].Name, river.Text)
+	}
+}
+
+func TestInfoboxHTML(t *testing.T) {
+	boxes := page.ParseInfoboxHTML(infoboxHTML)
+	if len(boxes) != 1 {
+		t.Fatalf("got %v infoboxes, expect 1", len(boxes))
+	}
+	box := boxes[0]
+	if box.Name != "infobox vcard" || box.Title != "Paris" {
+		t.Errorf("got %v (%v)", box.Name, box.Title)
+	}
+	if len(box.Fields) != 3 {
+		t.Fatalf("got %v fields, expect 3", len(box.Fields))
+	}
+	if v := box.Map()["Population"]; v != "2,102,650" {
+		t.Errorf("got %q, expect 2,102,650", v)
+	}
+	mayor, _ := box.Get("Mayor")
+	if mayor.Text != "Anne Hidalgo, X" || !utils.CompareSlice(mayor.Links, []string{"Anne Hidalgo"}) {
+		t.Errorf("got %q %v", mayor.Text, mayor.Links)
+	}
+	if country, _ := box.Get("Country"); country.Raw != `<a href="/wiki/France" title="France">France</a>` {
+		t.Errorf("got %v", country.Raw)
+	}
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestGetLangLinks(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	links, err := p.GetLangLinks()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("got %v links, expect 2", len(links))
+	}
+	if links[0].Lang != "de" || links[0].Title != "Ada Lovelace" || links[0].Autonym != "Deutsch" || links[0].URL != "https://de.wikipedia.org/wiki/Ada_Lovelace" {
+		t.Errorf("unexpected link %+v", links[0])
+	}
+	if link, ok := p.GetLangLink("ja"); !ok || link.Title != "エイダ・ラブレス" {
+		t.Errorf("unexpected link %+v", link)
+	}
+	if _, ok := p.GetLangLink("fr"); ok {
+		t.Errorf("the page has no french link")
+	}
+}
+
+func TestInLanguage(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	ja, err := p.InLanguage("ja")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ja.Title != "エイダ・ラブレス" || ja.PageID != 71820 || ja.Language != "ja" || ja.PageLanguage != "ja" {
+		t.Errorf("unexpected page %+v", ja)
+	}
+	summary, err := ja.GetSummary()
+	if err != nil || !strings.HasPrefix(summary, "オーガスタ") {
+		t.Errorf("got %v %v", summary, err)
+	}
+	if utils.WikiLanguage != "en" {
+		t.Errorf("the global language is changed to %v", utils.WikiLanguage)
+	}
+	if _, err := p.InLanguage("fr"); err == nil {
+		t.Errorf("expect an error for a missing language")
+	}
+}
+
+func TestRequestLanguageArg(t *testing.T) {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get(utils.LangArg) != "" {
+			t.Errorf("the language argument is sent to the API")
+		}
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"batchcomplete": ""}`))
+	}))
+	defer server.Close()
+	oldURL := utils.WikiURL
+	utils.WikiURL = server.URL + "/%v/api.php"
+	defer func() { utils.WikiURL = oldURL }()
+
+	_, err := utils.RequestWikiApi(map[string]string{"prop": "info", "titles": "Lang arg test", utils.LangArg: "de"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = utils.RequestWikiApi(map[string]string{"prop": "info", "titles": "Lang arg test"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(paths) != 2 || paths[0] != "/de/api.php" || paths[1] != "/en/api.php" {
+		t.Errorf("unexpected paths %v", paths)
+	}
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+)
+
+const markdownHTML = `<div class="mw-parser-output"><div role="note" class="hatnote navigation-not-searchable">For other uses, see <a href="/wiki/Ada_(disambiguation)">Ada</a>.</div>
+<p><b>Ada Lovelace</b> was an <i>English</i> <a href="/wiki/Mathematician" title="Mathematician">mathematician</a>.<sup id="cite_ref-1" class="reference"><a href="#cite_note-1">[1]</a></sup> See <a rel="nofollow" class="external text" href="https://example.org/a b">this site</a>.
+</p>
+<div id="toc" class="toc"><ul><li>1 Life</li></ul></div>
+<h2><span class="mw-headline" id="Life">Life</span><span class="mw-editsection"><span class="mw-editsection-bracket">[</span><a href="/w/index.php?title=Ada&amp;action=edit&amp;section=1">edit</a><span class="mw-editsection-bracket">]</span></span></h2>
+<ul><li>Born in <a href="//en.wikipedia.org/wiki/London">London</a>
+<ul><li>Nested</li></ul></li><li>Died in 1852</li></ul>
+<ol><li>First</li><li>Second</li></ol>
+<blockquote><p>The Analytical Engine weaves algebraic patterns.</p></blockquote>
+<table class="wikitable"><tr><th>Year</th><th>Work</th></tr><tr><td>1843</td><td>Notes | translation</td></tr></table>
+<div class="navbox"><a href="/wiki/Computing">Computing</a></div>
+</div>`
+
+func TestMarkdown(t *testing.T) {
+	p := page.WikipediaPage{HTML: markdownHTML, URL: "https://en.wikipedia.org/wiki/Ada_Lovelace"}
+	res, err := p.GetMarkdown(page.MarkdownOptions{})
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	expect := `**Ada Lovelace** was an *English* [mathematician](https://en.wikipedia.org/wiki/Mathematician). See [this site](https://example.org/a%20b).
+
+## Life
+
+- Born in [London](https://en.wikipedia.org/wiki/London)
+  - Nested
+- Died in 1852
+
+1. First
+2. Second
+
+> The Analytical Engine weaves algebraic patterns.
+
+| Year | Work |
+| --- | --- |
+| 1843 | Notes \| translation |
+`
+	if res != expect {
+		t.Errorf("got\n%v\nexpect\n%v", res, expect)
+	}
+}
+
+func TestMarkdownOptions(t *testing.T) {
+	res := page.RenderMarkdown(markdownHTML, page.MarkdownOptions{
+		BaseURL:              "https://fr.wikipedia.org",
+		KeepNavboxes:         true,
+		KeepHatnotes:         true,
+		KeepEditLinks:        true,
+		KeepReferenceMarkers: true,
+	})
+	for _, s := range []string{"For other uses, see [Ada](https://fr.wikipedia.org/wiki/Ada_(disambiguation))", `\[1\]`, "## Life\\[[edit](", "[Computing](https://fr.wikipedia.org/wiki/Computing)"} {
+		if !strings.Contains(res, s) {
+			t.Errorf("expect %q in\n%v", s, res)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func mockMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/metrics/pageviews/per-article/en.wikipedia/all-access/user/Ada_Lovelace/daily/20261001/20261002":
+			w.Write([]byte(`{"items": [
+				{"project": "en.wikipedia", "article": "Ada_Lovelace", "granularity": "daily", "timestamp": "2026100100", "access": "all-access", "agent": "user", "views": 5120},
+				{"project": "en.wikipedia", "article": "Ada_Lovelace", "granularity": "daily", "timestamp": "2026100200", "access": "all-access", "agent": "user", "views": 4875}]}`))
+		case "/metrics/pageviews/aggregate/de.wikipedia/mobile-web/all-agents/monthly/2026090100/2026093000":
+			w.Write([]byte(`{"items": [{"project": "de.wikipedia", "granularity": "monthly", "timestamp": "2026090100", "views": 512000000}]}`))
+		case "/metrics/pageviews/top/en.wikipedia/all-access/2026/10/01":
+			w.Write([]byte(`{"items": [{"project": "en.wikipedia", "access": "all-access", "year": "2026", "month": "10", "day": "01", "articles": [
+				{"article": "Main_Page", "views": 4500000, "rank": 1}, {"article": "Ada_Lovelace", "views": 98000, "rank": 2}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type": "https://mediawiki.org/wiki/HyperSwitch/errors/not_found", "title": "Not found.", "detail": "The date(s) you used are valid, but we either do not have data for those date(s), or the project you asked for is not loaded yet."}`))
+		}
+	}))
+	old := gowiki.MetricsURL
+	gowiki.MetricsURL = server.URL + "/metrics"
+	utils.Cache.Clear()
+	t.Cleanup(func() {
+		server.Close()
+		gowiki.MetricsURL = old
+		utils.Cache.Clear()
+	})
+}
+
+func TestGetPageviews(t *testing.T) {
+	mockMetrics(t)
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	views, err := gowiki.GetPageviews("Ada Lovelace", from, from.AddDate(0, 0, 1), "", "", "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(views) != 2 || views[0].Views != 5120 || !views[1].Timestamp.Equal(from.AddDate(0, 0, 1)) {
+		t.Errorf("unexpected views %+v", views)
+	}
+
+	_, err = gowiki.GetPageviews("Ada Lovelace", from.AddDate(1, 0, 0), from.AddDate(1, 0, 1), gowiki.GranularityDaily, gowiki.AccessAll, gowiki.AgentUser)
+	if err == nil || err.Error() != "The date(s) you used are valid, but we either do not have data for those date(s), or the project you asked for is not loaded yet." {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestGetProjectAndTopPageviews(t *testing.T) {
+	mockMetrics(t)
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	views, err := gowiki.GetProjectPageviews("de.wikipedia", from, from.AddDate(0, 0, 29), gowiki.GranularityMonthly, gowiki.AccessMobileWeb, gowiki.AgentAll)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(views) != 1 || views[0].Views != 512000000 || !views[0].Timestamp.Equal(from) {
+		t.Errorf("unexpected views %+v", views)
+	}
+
+	top, err := gowiki.GetTopPageviews(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(top) != 2 || top[1].Title != "Ada Lovelace" || top[1].Rank != 2 || top[0].Views != 4500000 {
+		t.Errorf("unexpected top articles %+v", top)
+	}
+}
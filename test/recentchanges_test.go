@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki"
+)
+
+func mockChange(rcid int, title string, timestamp string, flags ...string) map[string]interface{} {
+	change := map[string]interface{}{
+		"type": "edit", "ns": 0, "title": title, "pageid": rcid * 10, "revid": rcid * 100, "old_revid": rcid*100 - 1,
+		"rcid": rcid, "user": "Example", "oldlen": 1000, "newlen": 1000 + rcid, "timestamp": timestamp,
+		"comment": "copyedit", "tags": []string{"mobile edit"},
+	}
+	for _, f := range flags {
+		change[f] = ""
+	}
+	return change
+}
+
+// Serve 2 polls: the first one in 2 parts, the second one repeating the last change of the first one
+func recentChangesHandler(requests *[]url.Values) func(q url.Values) interface{} {
+	return func(q url.Values) interface{} {
+		*requests = append(*requests, q)
+		switch {
+		case q.Get("rcstart") == "2026-10-19T10:00:00Z":
+			return map[string]interface{}{
+				"continue": map[string]string{"rccontinue": "20261019100002|3", "continue": "-||"},
+				"query": map[string]interface{}{"recentchanges": []interface{}{
+					mockChange(1, "Ada Lovelace", "2026-10-19T10:00:01Z", "minor"),
+					mockChange(2, "Charles Babbage", "2026-10-19T10:00:02Z", "bot"),
+				}},
+			}
+		case q.Get("rccontinue") == "20261019100002|3":
+			return map[string]interface{}{"query": map[string]interface{}{"recentchanges": []interface{}{
+				mockChange(3, "Ada Lovelace", "2026-10-19T10:00:02Z", "anon"),
+			}}}
+		case q.Get("rcstart") == "2026-10-19T10:00:02Z":
+			return map[string]interface{}{"query": map[string]interface{}{"recentchanges": []interface{}{
+				mockChange(3, "Ada Lovelace", "2026-10-19T10:00:02Z", "anon"),
+				mockChange(4, "Analytical Engine", "2026-10-19T10:00:05Z"),
+			}}}
+		}
+		return map[string]interface{}{"query": map[string]interface{}{"recentchanges": []interface{}{}}}
+	}
+}
+
+func TestRecentChangesIterator(t *testing.T) {
+	requests := []url.Values{}
+	MockAPIServer(t, recentChangesHandler(&requests))
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	stream := gowiki.NewRecentChangesStream(gowiki.RecentChangesOptions{
+		Namespaces:   []int{0},
+		Types:        []string{gowiki.ChangeEdit, gowiki.ChangeNew},
+		Bot:          gowiki.FlagAny,
+		Minor:        gowiki.FlagAny,
+		Anon:         gowiki.FlagAny,
+		Checkpoint:   gowiki.Checkpoint{Timestamp: start},
+		PollInterval: 10 * time.Millisecond,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rcids := []int{}
+	for i := 0; i < 4; i++ {
+		change, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		rcids = append(rcids, change.RCID)
+		if i == 0 && (!change.Minor || change.Bot || change.RevID != 100 || change.OldRevID != 99 || change.SizeDelta != 1 || change.User != "Example") {
+			t.Errorf("unexpected change %+v", change)
+		}
+		if i == 2 && !change.Anon {
+			t.Errorf("unexpected change %+v", change)
+		}
+	}
+	if len(rcids) != 4 || rcids[0] != 1 || rcids[2] != 3 || rcids[3] != 4 {
+		t.Errorf("unexpected changes %v", rcids)
+	}
+	if requests[0].Get("rcnamespace") != "0" || requests[0].Get("rctype") != "edit|new" || requests[0].Get("rcdir") != "newer" || requests[0].Has("_nocache") {
+		t.Errorf("unexpected request %v", requests[0])
+	}
+	checkpoint := stream.Checkpoint()
+	if checkpoint.RCID != 4 || !checkpoint.Timestamp.Equal(start.Add(5*time.Second)) {
+		t.Errorf("unexpected checkpoint %+v", checkpoint)
+	}
+}
+
+func TestRecentChangesChannel(t *testing.T) {
+	requests := []url.Values{}
+	MockAPIServer(t, recentChangesHandler(&requests))
+	stream := gowiki.NewRecentChangesStream(gowiki.RecentChangesOptions{
+		Bot:          gowiki.FlagExclude,
+		Anon:         gowiki.FlagOnly,
+		Tag:          "mobile edit",
+		Titles:       []string{"Ada Lovelace", "Analytical_Engine"},
+		Checkpoint:   gowiki.Checkpoint{Timestamp: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)},
+		PollInterval: 10 * time.Millisecond,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	titles := []string{}
+	for change := range stream.Changes(ctx) {
+		titles = append(titles, change.Title)
+		if len(titles) == 1 {
+			// The next change (rcid 3) waits on the channel, so the checkpoint must stay before it
+			time.Sleep(50 * time.Millisecond)
+			if c := stream.Checkpoint(); c.RCID < change.RCID || c.RCID >= 3 {
+				t.Errorf("got checkpoint %+v, expect one before the change 3", c)
+			}
+		}
+		if len(titles) == 3 {
+			cancel()
+		}
+	}
+	cancel()
+	if stream.Err() != nil {
+		t.Errorf("%v", stream.Err())
+	}
+	if len(titles) != 3 || titles[0] != "Ada Lovelace" || titles[2] != "Analytical Engine" {
+		t.Errorf("unexpected titles %v", titles)
+	}
+	if requests[0].Get("rcshow") != "!bot|anon" || requests[0].Get("rctag") != "mobile edit" || requests[0].Has("rctitle") {
+		t.Errorf("unexpected request %v", requests[0])
+	}
+}
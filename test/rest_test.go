@@ -0,0 +1,110 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki/rest"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+const mockSummary = `{
+	"type": "standard", "title": "Ada Lovelace", "displaytitle": "<span class=\"mw-page-title-main\">Ada Lovelace</span>",
+	"namespace": {"id": 0, "text": ""}, "wikibase_item": "Q7259", "pageid": 974, "lang": "en", "dir": "ltr",
+	"revision": "1249001234", "timestamp": "2026-10-01T12:30:00Z",
+	"description": "English mathematician (1815–1852)", "description_source": "local",
+	"thumbnail": {"source": "https://upload.wikimedia.org/thumb/Ada_Lovelace_portrait.jpg/320px-Ada_Lovelace_portrait.jpg", "width": 320, "height": 400},
+	"coordinates": {"lat": 51.5, "lon": -0.12},
+	"content_urls": {"desktop": {"page": "https://en.wikipedia.org/wiki/Ada_Lovelace", "edit": "https://en.wikipedia.org/wiki/Ada_Lovelace?action=edit"}},
+	"extract": "Augusta Ada King, Countess of Lovelace was an English mathematician and writer."
+}`
+
+func mockREST(t *testing.T) map[string]int {
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.EscapedPath()]++
+		switch r.URL.EscapedPath() {
+		case "/en/api/rest_v1/page/summary/Ada_Lovelace":
+			w.Write([]byte(mockSummary))
+		case "/de/api/rest_v1/page/summary/Ada_Lovelace":
+			w.Write([]byte(`{"type": "standard", "title": "Ada Lovelace", "pageid": 11111, "lang": "de", "extract": "Ada Lovelace war eine britische Mathematikerin."}`))
+		case "/en/api/rest_v1/page/html/Ada_Lovelace":
+			w.Write([]byte(`<html><body><section data-mw-section-id="0"><p>Ada</p></section></body></html>`))
+		case "/en/api/rest_v1/page/mobile-html/Ada_Lovelace":
+			w.Write([]byte(`<html><body class="mobile"></body></html>`))
+		case "/en/api/rest_v1/page/related/Ada_Lovelace":
+			w.Write([]byte(`{"pages": [{"title": "Charles Babbage", "pageid": 4242}, {"title": "Analytical Engine", "pageid": 1271}]}`))
+		case "/en/api/rest_v1/page/media-list/Ada_Lovelace":
+			w.Write([]byte(`{"revision": "1249001234", "items": [{"title": "File:Ada_Lovelace_portrait.jpg", "leadImage": true, "section_id": 0, "type": "image", "showInGallery": true,
+				"caption": {"html": "Portrait", "text": "Portrait"}, "srcset": [{"src": "//upload.wikimedia.org/Ada.jpg", "scale": "1x"}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type": "https://mediawiki.org/wiki/HyperSwitch/errors/not_found", "title": "Not found.", "detail": "Page or revision not found."}`))
+		}
+	}))
+	old := rest.BaseURL
+	rest.BaseURL = server.URL + "/%v/api/rest_v1"
+	utils.Cache.Clear()
+	t.Cleanup(func() {
+		server.Close()
+		rest.BaseURL = old
+		utils.Cache.Clear()
+	})
+	return hits
+}
+
+func TestRESTSummary(t *testing.T) {
+	hits := mockREST(t)
+	p, err := rest.GetPage("", "Ada Lovelace")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if p.PageID != 974 || p.Summary != "Augusta Ada King, Countess of Lovelace was an English mathematician and writer." || p.URL != "https://en.wikipedia.org/wiki/Ada_Lovelace" {
+		t.Errorf("unexpected page %+v", p)
+	}
+	if p.WikidataID != "Q7259" || p.LastRevID != 1249001234 || len(p.Coordinate) != 2 {
+		t.Errorf("unexpected page %+v", p)
+	}
+	if !p.LastRevTimestamp.Equal(time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)) || !p.Touched.IsZero() {
+		t.Errorf("unexpected page %+v", p)
+	}
+	// The second call is served by the cache
+	summary, err := rest.GetSummary("", "Ada Lovelace")
+	if err != nil || summary.Thumbnail.Width != 320 || summary.Description != "English mathematician (1815–1852)" {
+		t.Errorf("unexpected summary %+v %v", summary, err)
+	}
+	if hits["/en/api/rest_v1/page/summary/Ada_Lovelace"] != 1 {
+		t.Errorf("the summary is requested %v times", hits["/en/api/rest_v1/page/summary/Ada_Lovelace"])
+	}
+
+	de, err := rest.GetPage("de", "Ada Lovelace")
+	if err != nil || de.PageID != 11111 || de.Language != "de" {
+		t.Errorf("unexpected page %+v %v", de, err)
+	}
+}
+
+func TestRESTEndpoints(t *testing.T) {
+	mockREST(t)
+	p, _ := rest.GetPage("", "Ada Lovelace")
+	html, err := rest.LoadHTML(&p)
+	if err != nil || p.HTML != html || html == "" {
+		t.Errorf("unexpected html %v %v", html, err)
+	}
+	if mobile, err := rest.GetMobileHTML("", "Ada Lovelace"); err != nil || mobile != `<html><body class="mobile"></body></html>` {
+		t.Errorf("unexpected html %v %v", mobile, err)
+	}
+	related, err := rest.GetRelated("", "Ada Lovelace")
+	if err != nil || len(related) != 2 || related[0].Title != "Charles Babbage" {
+		t.Errorf("unexpected related pages %+v %v", related, err)
+	}
+	media, err := rest.GetMediaList("", "Ada Lovelace")
+	if err != nil || len(media) != 1 || !media[0].LeadImage || media[0].SrcSet[0].Scale != "1x" || media[0].Caption.Text != "Portrait" {
+		t.Errorf("unexpected media %+v %v", media, err)
+	}
+	_, err = rest.GetSummary("", "Missing page")
+	if err == nil || err.Error() != "Page or revision not found." {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestSectionTree(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	tree, err := p.GetSectionTree()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(tree) != 3 {
+		t.Fatalf("got %v top level sections, expect 3", len(tree))
+	}
+	bio := tree[0]
+	if bio.Title != "Biography" || len(bio.Children) != 2 || bio.Children[1].Title != "Adult years" {
+		t.Errorf("unexpected section %v", bio)
+	}
+	notes := bio.Children[1].Children
+	if len(notes) != 1 || notes[0].Title != "Notes" || notes[0].Level != 4 || notes[0].Number != "1.2.1" || notes[0].ByteOffset != 7120 {
+		t.Errorf("unexpected subsections %v", notes)
+	}
+	if tree[2].Index != "T-1" || tree[2].ByteOffset != -1 {
+		t.Errorf("unexpected transcluded section %v", tree[2])
+	}
+
+	// Duplicated titles are reached with the anchor
+	s, err := p.FindSection("Notes")
+	if err != nil || s.Number != "1.2.1" {
+		t.Errorf("got %v %v, expect section 1.2.1", s, err)
+	}
+	s, err = p.FindSection("Notes_2")
+	if err != nil || s.Number != "2" {
+		t.Errorf("got %v %v, expect section 2", s, err)
+	}
+	if _, err = p.FindSection("History"); err == nil {
+		t.Errorf("expect section not exist")
+	}
+}
+
+func TestSectionContent(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	s, err := p.GetSectionNode("Adult years")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expect := "She married William King in 1835.\nNotes\nHer notes describe an algorithm."
+	if s.Text != expect {
+		t.Errorf("got %q, expect %q", s.Text, expect)
+	}
+	if len(s.Children) != 1 {
+		t.Errorf("expect the subsection to be kept")
+	}
+}
+
+func TestSectionDuplicatedAndNested(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	p.Content = "Intro.\n\n== Biography ==\n\n=== Childhood ===\nBorn in 1815.\n\n=== Adult years ===\nShe married in 1835.\n\n" +
+		"==== Notes ====\nHer notes.\n\n== Notes ==\nFirst note.\n\n== References =="
+	for title, expect := range map[string]string{
+		"Adult years": "She married in 1835.",
+		"Notes":       "Her notes.",
+		"Notes_2":     "First note.",
+		"Biography":   "",
+	} {
+		if content, err := p.GetSection(title); err != nil || content != expect {
+			t.Errorf("section %v: got %q %v, expect %q", title, content, err, expect)
+		}
+	}
+	sections, err := p.GetSectionList()
+	if err != nil || len(sections) != 6 || sections[3] != "Notes" {
+		t.Errorf("unexpected sections %v %v", sections, err)
+	}
+}
+
+func TestTranscludedSectionContent(t *testing.T) {
+	var last url.Values
+	MockAPIServer(t, func(q url.Values) interface{} {
+		last = q
+		return map[string]interface{}{"parse": map[string]interface{}{"wikitext": map[string]string{"*": "== References ==\nThe list of references."}}}
+	})
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	s := &page.Section{Title: "References", Index: "T-1", FromTitle: "Template:Reflist", ByteOffset: -1}
+	text, err := p.GetSectionContent(s)
+	if err != nil || text != "The list of references." {
+		t.Errorf("got %q %v", text, err)
+	}
+	if last.Get("page") != "Template:Reflist" || last.Get("section") != "1" {
+		t.Errorf("the section is not loaded from the template: %v", last)
+	}
+}
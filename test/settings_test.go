@@ -5,6 +5,7 @@ import (
 
 	"github.com/trietmn/go-wiki"
 	"github.com/trietmn/go-wiki/cache"
+	"github.com/trietmn/go-wiki/models"
 	"github.com/trietmn/go-wiki/utils"
 )
 
@@ -40,3 +41,30 @@ func TestCache(t *testing.T) {
 		t.Errorf("expect request got added to the cache")
 	}
 }
+
+func TestCacheTitleKeys(t *testing.T) {
+	c := cache.MakeWikiCache()
+	res := models.RequestResult{}
+	c.Add("a", res)
+	c.Tag("a", "Ada Lovelace")
+	c.Tag("a", "Ada_Lovelace")
+	if len(c.TitleKeys["Ada Lovelace"]) != 1 {
+		t.Errorf("the key is tagged %v times, expect 1", len(c.TitleKeys["Ada Lovelace"]))
+	}
+	c.Pop()
+	if len(c.TitleKeys) != 0 || len(c.KeyTitles) != 0 {
+		t.Errorf("the popped key is still tagged: %v", c.TitleKeys)
+	}
+
+	old := cache.CacheExpiration
+	defer func() { cache.CacheExpiration = old }()
+	c.Add("b", res)
+	c.Tag("b", "Charles Babbage")
+	cache.CacheExpiration = 0
+	if _, err := c.Get("b"); err == nil {
+		t.Errorf("the outdated request is returned")
+	}
+	if len(c.TitleKeys) != 0 || c.GetLen() != 0 {
+		t.Errorf("the outdated key is still tagged: %v", c.TitleKeys)
+	}
+}
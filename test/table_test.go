@@ -0,0 +1,80 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/trietmn/go-wiki/page"
+)
+
+const tableHTML = `<table class="wikitable sortable"><caption>Tallest buildings<sup class="reference">[1]</sup></caption>
+<thead><tr><th rowspan="2">Name</th><th colspan="2">Height</th></tr>
+<tr><th>m</th><th>ft</th></tr></thead>
+<tbody><tr><td>Burj Khalifa<sup class="reference"><a href="#cite_note-2">[2]</a></sup></td><td rowspan="2">828</td><td>2,717</td></tr>
+<tr><td>Twin, "copy"</td><td>2,717</td></tr>
+<tr><td colspan="3">Total<table class="nested"><tr><td>x</td></tr></table></td></tr></tbody></table>
+<table class="infobox"><tr><td>ignored</td></tr></table>`
+
+func TestTables(t *testing.T) {
+	p := page.WikipediaPage{HTML: tableHTML}
+	tables, err := p.GetTables()
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("got %v tables, expect 1", len(tables))
+	}
+	table := tables[0]
+	if table.Caption != "Tallest buildings" {
+		t.Errorf("got %q, expect Tallest buildings", table.Caption)
+	}
+	header := [][]string{{"Name", "Height", "Height"}, {"Name", "m", "ft"}}
+	rows := [][]string{{"Burj Khalifa", "828", "2,717"}, {`Twin, "copy"`, "828", "2,717"}, {"Total x", "Total x", "Total x"}}
+	check := func(got, expect [][]string) {
+		if len(got) != len(expect) {
+			t.Fatalf("got %v, expect %v", got, expect)
+		}
+		for i := range expect {
+			for j := range expect[i] {
+				if len(got[i]) != len(expect[i]) || got[i][j] != expect[i][j] {
+					t.Errorf("got %v, expect %v", got[i], expect[i])
+					break
+				}
+			}
+		}
+	}
+	check(table.Header, header)
+	check(table.Rows, rows)
+
+	csv, err := table.CSV()
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	expectCSV := "Name,Height,Height\nName,m,ft\nBurj Khalifa,828,\"2,717\"\n\"Twin, \"\"copy\"\"\",828,\"2,717\"\nTotal x,Total x,Total x\n"
+	if csv != expectCSV {
+		t.Errorf("got %q, expect %q", csv, expectCSV)
+	}
+	records := table.Records()
+	if len(records) != 3 || records[0]["Name"] != "Burj Khalifa" || records[0]["ft"] != "2,717" {
+		t.Errorf("unexpected records %v", records)
+	}
+	data, err := table.JSON()
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	var decoded page.Table
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Caption != table.Caption || len(decoded.Rows) != 3 {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
+
+func TestTableZeroColspan(t *testing.T) {
+	p := page.WikipediaPage{HTML: `<table class="wikitable"><tr><th>A</th><th>B</th></tr><tr><td colspan="0">x</td><td>y</td></tr></table>`}
+	tables, err := p.GetTables()
+	if err != nil || len(tables) != 1 {
+		t.Fatalf("got %v tables, %v", len(tables), err)
+	}
+	if rows := tables[0].Rows; len(rows) != 1 || len(rows[0]) != 2 || rows[0][0] != "x" || rows[0][1] != "y" {
+		t.Errorf("got %v, expect [[x y]]", rows)
+	}
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/trietmn/go-wiki"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestGetTemplates(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	templates, err := p.GetTemplates()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expect := "Template:Infobox person|Template:Cite book|Module:Citation/CS1"
+	if strings.Join(templates, "|") != expect {
+		t.Errorf("got %v, expect %v", templates, expect)
+	}
+
+	p = page.WikipediaPage{Title: "Ada Lovelace (templates only)", PageID: 975001}
+	templates, err = p.GetTemplates(page.TemplateNamespace)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(templates) != 2 {
+		t.Errorf("got %v templates, expect 2", len(templates))
+	}
+}
+
+func TestGetTranscludedIn(t *testing.T) {
+	requests := []url.Values{}
+	MockAPIServer(t, func(q url.Values) interface{} {
+		requests = append(requests, q)
+		if q.Get("eicontinue") == "" {
+			return map[string]interface{}{
+				"continue": map[string]string{"eicontinue": "0|4242", "continue": "-||"},
+				"query":    map[string]interface{}{"embeddedin": []map[string]interface{}{{"pageid": 974, "ns": 0, "title": "Ada Lovelace"}}},
+			}
+		}
+		return map[string]interface{}{
+			"batchcomplete": "",
+			"query":         map[string]interface{}{"embeddedin": []map[string]interface{}{{"pageid": 4242, "ns": 0, "title": "Charles Babbage"}}},
+		}
+	})
+	titles, err := gowiki.GetTranscludedIn("Infobox person", 0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if strings.Join(titles, "|") != "Ada Lovelace|Charles Babbage" {
+		t.Errorf("unexpected titles %v", titles)
+	}
+	if len(requests) != 2 || requests[0].Get("eititle") != "Template:Infobox person" || requests[0].Get("einamespace") != "0" || requests[1].Get("eicontinue") != "0|4242" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gowiki "github.com/trietmn/go-wiki"
+)
+
+func TestUploadFile(t *testing.T) {
+	var lock sync.Mutex
+	stash := map[string][]byte{}
+	requests := []map[string]string{}
+	published := ""
+	MockHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		r.ParseMultipartForm(32 << 20)
+		q := map[string]string{}
+		for k := range r.Form {
+			q[k] = r.Form.Get(k)
+		}
+		requests = append(requests, q)
+		var res interface{}
+		upload := func(result map[string]interface{}) interface{} {
+			return map[string]interface{}{"upload": result}
+		}
+		readFile := func(field string) []byte {
+			file, _, err := r.FormFile(field)
+			if err != nil {
+				t.Errorf("no %v in the form: %v", field, err)
+				return nil
+			}
+			defer file.Close()
+			data, _ := ioutil.ReadAll(file)
+			return data
+		}
+		success := func(filename string, data []byte) interface{} {
+			return upload(map[string]interface{}{"result": "Success", "filename": filename, "imageinfo": map[string]interface{}{
+				"url": "https://upload.example.org/" + filename, "size": len(data), "mime": "image/png",
+			}})
+		}
+		switch {
+		case q["meta"] == "tokens":
+			res = map[string]interface{}{"query": map[string]interface{}{"tokens": map[string]string{"csrftoken": "+\\"}}}
+		case q["stash"] == "1":
+			key := q["filekey"]
+			if key == "" {
+				key = "stash.1"
+			}
+			if q["offset"] != strconv.Itoa(len(stash[key])) {
+				res = map[string]interface{}{"error": map[string]string{"code": "stashfailed", "info": "wrong offset"}}
+				break
+			}
+			stash[key] = append(stash[key], readFile("chunk")...)
+			result := "Continue"
+			if q["filesize"] == strconv.Itoa(len(stash[key])) {
+				result = "Success"
+			}
+			res = upload(map[string]interface{}{"result": result, "filekey": key, "offset": len(stash[key])})
+		case q["checkstatus"] == "1":
+			res = success(published, stash[q["filekey"]])
+		case q["filekey"] != "" && q["async"] == "1":
+			// The file is published in the background
+			published = q["filename"]
+			res = upload(map[string]interface{}{"result": "Poll", "stage": "queued", "filekey": q["filekey"]})
+		case q["filekey"] != "":
+			res = success(q["filename"], stash[q["filekey"]])
+		case q["filename"] == "Duplicate.png" && q["ignorewarnings"] == "":
+			readFile("file")
+			res = upload(map[string]interface{}{"result": "Warning", "filekey": "dup.1", "warnings": map[string]interface{}{"duplicate": []string{"Original.png"}}})
+		default:
+			res = success(q["filename"], readFile("file"))
+		}
+		json.NewEncoder(w).Encode(res)
+	})
+
+	if _, err := gowiki.UploadFile("Small.png", strings.NewReader("png"), "A file", "cc0", gowiki.UploadOptions{}); err == nil {
+		t.Errorf("the upload works without a session")
+	}
+	activateTestSession(t)
+
+	// Small file: a single request
+	res, err := gowiki.UploadFile("Small.png", strings.NewReader("small png"), "A small file", "cc-by-sa-4.0", gowiki.UploadOptions{Comment: "upload"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if res.Filename != "Small.png" || res.Size != 9 || res.URL == "" {
+		t.Errorf("wrong result %+v", res)
+	}
+	last := requests[len(requests)-1]
+	if last["text"] != "A small file\n\n== {{int:license-header}} ==\n{{cc-by-sa-4.0}}" || last["comment"] != "upload" || last["token"] != "+\\" {
+		t.Errorf("wrong upload arguments %v", last)
+	}
+
+	// Duplicate: the warning stops the upload unless the warnings are ignored
+	_, err = gowiki.UploadFile("Duplicate.png", bytes.NewReader([]byte("same")), "Copy", "", gowiki.UploadOptions{})
+	var warning *gowiki.UploadWarningError
+	if !errors.As(err, &warning) || len(warning.Duplicates) != 1 || warning.Duplicates[0] != "Original.png" || warning.FileKey != "dup.1" {
+		t.Errorf("expected a duplicate warning, got %v", err)
+	}
+	if _, err := gowiki.UploadFile("Duplicate.png", bytes.NewReader([]byte("same")), "Copy", "", gowiki.UploadOptions{IgnoreWarnings: true}); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	// Large file of unknown size: uploaded in chunks of 4 bytes
+	content := "0123456789"
+	progress := []int64{}
+	reader := io.MultiReader(strings.NewReader(content[:5]), strings.NewReader(content[5:]))
+	res, err = gowiki.UploadFile("Large.png", reader, "A large file", "cc0", gowiki.UploadOptions{
+		ChunkSize: 4,
+		Progress: func(sent int64, total int64) {
+			if total != 10 {
+				t.Errorf("wrong total %v", total)
+			}
+			progress = append(progress, sent)
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if string(stash["stash.1"]) != content || res.Size != 10 {
+		t.Errorf("wrong stashed file %q", stash["stash.1"])
+	}
+	if len(progress) != 3 || progress[2] != 10 {
+		t.Errorf("wrong progress %v", progress)
+	}
+	publish, last := requests[len(requests)-2], requests[len(requests)-1]
+	if publish["filekey"] != "stash.1" || publish["text"] == "" || publish["stash"] != "" || publish["async"] != "1" {
+		t.Errorf("wrong publish request %v", publish)
+	}
+	if last["checkstatus"] != "1" || last["filekey"] != "stash.1" || res.Filename != "Large.png" {
+		t.Errorf("wrong final request %v", last)
+	}
+}
+
+func TestUploadSlowFile(t *testing.T) {
+	MockAPIServer(t, func(q url.Values) interface{} {
+		if q.Get("meta") == "tokens" {
+			return map[string]interface{}{"query": map[string]interface{}{"tokens": map[string]string{"csrftoken": "+\\"}}}
+		}
+		// The file takes longer to send than the timeout of the client
+		time.Sleep(100 * time.Millisecond)
+		return map[string]interface{}{"upload": map[string]interface{}{"result": "Success", "filename": q.Get("filename")}}
+	})
+	session := activateTestSession(t)
+	session.Client.Timeout = 50 * time.Millisecond
+	if _, err := gowiki.UploadFile("Slow.png", strings.NewReader("slow png"), "A slow file", "", gowiki.UploadOptions{}); err != nil {
+		t.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	gowiki "github.com/trietmn/go-wiki"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+func TestGetUser(t *testing.T) {
+	var last url.Values
+	MockAPIServer(t, func(q url.Values) interface{} {
+		last = q
+		users := []map[string]interface{}{}
+		switch q.Get("ususers") {
+		case "Example":
+			users = append(users, map[string]interface{}{
+				"userid": 42, "name": "Example", "editcount": 1500, "registration": "2010-05-01T12:00:00Z",
+				"groups": []string{"*", "user", "autoconfirmed"}, "gender": "female",
+			})
+		case "Vandal":
+			users = append(users, map[string]interface{}{
+				"userid": 43, "name": "Vandal", "editcount": 3, "registration": nil, "groups": []string{"*", "user"}, "gender": "unknown",
+				"blockid": 99, "blockedby": "Admin", "blockreason": "Vandalism", "blockedtimestamp": "2026-10-01T00:00:00Z",
+				"blockexpiry": "infinite", "blockpartial": "",
+			})
+		default:
+			users = append(users, map[string]interface{}{"name": q.Get("ususers"), "missing": ""})
+		}
+		return map[string]interface{}{"query": map[string]interface{}{"users": users}}
+	})
+
+	user, err := gowiki.GetUser("Example")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if last.Get("usprop") != "editcount|registration|groups|blockinfo|gender" {
+		t.Errorf("wrong usprop %v", last.Get("usprop"))
+	}
+	if user.UserID != 42 || user.EditCount != 1500 || user.Gender != "female" || len(user.Groups) != 3 || user.Block != nil {
+		t.Errorf("wrong user %+v", user)
+	}
+	if !user.Registration.Equal(time.Date(2010, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong registration %v", user.Registration)
+	}
+
+	user, err = gowiki.GetUser("Vandal")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if user.Block == nil || user.Block.ID != 99 || user.Block.By != "Admin" || !user.Block.Expiry.IsZero() || !user.Block.Partial || !user.Registration.IsZero() {
+		t.Errorf("wrong block %+v", user.Block)
+	}
+
+	var missing *utils.MissingPageError
+	if _, err := gowiki.GetUser("Nobody"); !errors.As(err, &missing) {
+		t.Errorf("expected a missing user error, got %v", err)
+	}
+}
+
+func TestGetUserContribs(t *testing.T) {
+	requests := []url.Values{}
+	MockAPIServer(t, func(q url.Values) interface{} {
+		requests = append(requests, q)
+		if q.Get("uccontinue") == "" {
+			return map[string]interface{}{
+				"continue": map[string]string{"uccontinue": "20261018120000|500", "continue": "-||"},
+				"query": map[string]interface{}{"usercontribs": []map[string]interface{}{
+					{"user": "Example", "pageid": 974, "revid": 502, "parentid": 501, "ns": 0, "title": "Ada Lovelace", "timestamp": "2026-10-19T10:00:00Z", "comment": "typo", "size": 1000, "sizediff": -2, "minor": "", "top": ""},
+					{"user": "Example", "pageid": 975, "revid": 501, "parentid": 0, "ns": 0, "title": "Charles Babbage", "timestamp": "2026-10-18T12:00:00Z", "size": 500, "sizediff": 500, "new": ""},
+				}},
+			}
+		}
+		return map[string]interface{}{"query": map[string]interface{}{"usercontribs": []map[string]interface{}{
+			{"user": "Example", "pageid": 974, "revid": 500, "parentid": 400, "ns": 0, "title": "Ada Lovelace", "timestamp": "2026-10-18T11:00:00Z", "size": 1002, "sizediff": 10},
+		}}}
+	})
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	stream := gowiki.GetUserContribs("Example", gowiki.UserContribsOptions{Namespaces: []int{0, 2}, Since: since, Until: until})
+	contribs := []gowiki.UserContrib{}
+	for {
+		contrib, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		contribs = append(contribs, contrib)
+	}
+	if len(contribs) != 3 || len(requests) != 2 {
+		t.Fatalf("expected 3 contributions in 2 requests, got %v in %v", len(contribs), len(requests))
+	}
+	q := requests[0]
+	if q.Get("ucuser") != "Example" || q.Get("ucnamespace") != "0|2" || q.Get("ucstart") != "2026-10-20T00:00:00Z" || q.Get("ucend") != "2026-10-01T00:00:00Z" || q.Get("ucdir") != "" {
+		t.Errorf("wrong arguments %v", q)
+	}
+	if c := contribs[0]; c.RevID != 502 || !c.Minor || !c.Top || c.New || c.SizeDelta != -2 || c.Timestamp.IsZero() {
+		t.Errorf("wrong contribution %+v", c)
+	}
+	if !contribs[1].New || contribs[2].RevID != 500 {
+		t.Errorf("wrong contributions %+v", contribs)
+	}
+
+	// The contributions are never cached
+	stream = gowiki.GetUserContribs("Example", gowiki.UserContribsOptions{Namespaces: []int{0, 2}, Since: since, Until: until})
+	if _, err := stream.Next(); err != nil || len(requests) != 3 {
+		t.Errorf("expected a new request, got %v requests: %v", len(requests), err)
+	}
+
+	// Oldest first, limited, on a channel
+	requests = requests[:0]
+	stream = gowiki.GetUserContribs("Example", gowiki.UserContribsOptions{Since: since, OldestFirst: true, Limit: 1})
+	contribs = contribs[:0]
+	for c := range stream.Contribs(context.Background()) {
+		contribs = append(contribs, c)
+	}
+	if stream.Err() != nil || len(contribs) != 1 || len(requests) != 1 {
+		t.Errorf("expected 1 contribution in 1 request, got %v in %v: %v", len(contribs), len(requests), stream.Err())
+	}
+	if q := requests[0]; q.Get("ucdir") != "newer" || q.Get("ucstart") != "2026-10-01T00:00:00Z" || q.Get("uclimit") != "1" {
+		t.Errorf("wrong arguments %v", q)
+	}
+}
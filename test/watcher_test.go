@@ -0,0 +1,172 @@
+package test
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki/utils"
+	"github.com/trietmn/go-wiki/watcher"
+)
+
+func TestWatcher(t *testing.T) {
+	var lock sync.Mutex
+	revisions := map[string]int{"Ada Lovelace": 100, "Charles Babbage": 200}
+	extracts := 0
+	MockAPIServer(t, func(q url.Values) interface{} {
+		lock.Lock()
+		defer lock.Unlock()
+		if q.Get("prop") == "extracts" {
+			extracts++
+		}
+		if q.Get("action") == "compare" {
+			return map[string]interface{}{"compare": map[string]interface{}{
+				"fromrevid": 100, "torevid": 105, "*": "<tr><td>diff " + q.Get("fromrev") + " " + q.Get("torev") + "</td></tr>",
+			}}
+		}
+		pages := map[string]interface{}{}
+		normalized := []map[string]string{}
+		for i, title := range strings.Split(q.Get("titles"), "|") {
+			if title == "charles Babbage" {
+				normalized = append(normalized, map[string]string{"from": title, "to": "Charles Babbage"})
+				title = "Charles Babbage"
+			}
+			if q.Get("prop") == "info" {
+				pages[strings.Repeat("1", i+1)] = map[string]interface{}{
+					"pageid": i + 1, "title": title, "lastrevid": revisions[title], "touched": "2026-10-19T10:00:00Z",
+				}
+			} else {
+				pages[strings.Repeat("1", i+1)] = map[string]interface{}{"pageid": i + 1, "title": title, "extract": "cached"}
+			}
+		}
+		return map[string]interface{}{"query": map[string]interface{}{"normalized": normalized, "pages": pages}}
+	})
+
+	statePath := filepath.Join(t.TempDir(), "watch.json")
+	changes := []watcher.Change{}
+	w, err := watcher.New(watcher.Options{Diff: true, StatePath: statePath}, func(c watcher.Change) {
+		changes = append(changes, c)
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	w.Add("Ada_Lovelace", "charles_Babbage")
+	// The first check only records the revisions
+	if err := w.Check(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if s, ok := w.State("charles_Babbage"); !ok || s.RevID != 200 {
+		t.Errorf("unexpected state %+v", s)
+	}
+
+	// Fill the cache with a request about the page
+	args := map[string]string{"prop": "extracts", "titles": "Ada Lovelace"}
+	utils.RequestWikiApi(args)
+	utils.RequestWikiApi(args)
+	if extracts != 1 {
+		t.Fatalf("the request is not cached")
+	}
+
+	lock.Lock()
+	revisions["Ada Lovelace"] = 105
+	lock.Unlock()
+	if err := w.Check(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("got %v changes, expect 1", len(changes))
+	}
+	c := changes[0]
+	if c.Title != "Ada Lovelace" || c.OldRevID != 100 || c.NewRevID != 105 || c.Diff != "<tr><td>diff 100 105</td></tr>" {
+		t.Errorf("unexpected change %+v", c)
+	}
+	utils.RequestWikiApi(args)
+	if extracts != 2 {
+		t.Errorf("the cache of the page is not invalidated")
+	}
+
+	// A new watcher resumes from the saved state
+	changes = changes[:0]
+	w2, err := watcher.New(watcher.Options{StatePath: statePath}, func(c watcher.Change) {
+		changes = append(changes, c)
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(w2.Titles()) != 2 {
+		t.Errorf("unexpected titles %v", w2.Titles())
+	}
+	lock.Lock()
+	revisions["Charles Babbage"] = 201
+	lock.Unlock()
+	if err := w2.Check(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(changes) != 1 || changes[0].Title != "charles Babbage" || changes[0].OldRevID != 200 || changes[0].Diff != "" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
+
+func TestWatcherRunConcurrently(t *testing.T) {
+	var lock sync.Mutex
+	checks := 0
+	MockAPIServer(t, func(q url.Values) interface{} {
+		lock.Lock()
+		defer lock.Unlock()
+		if q.Get("prop") != "info" {
+			return map[string]interface{}{"query": map[string]interface{}{"pages": map[string]interface{}{
+				"1": map[string]interface{}{"pageid": 1, "title": q.Get("titles"), "extract": "text"},
+			}}}
+		}
+		checks++
+		// Every third check fails, the next ones go on
+		if checks%3 == 0 {
+			return map[string]interface{}{"error": map[string]string{"code": "internal_api_error", "info": "server error"}}
+		}
+		return map[string]interface{}{"query": map[string]interface{}{"pages": map[string]interface{}{
+			"1": map[string]interface{}{"pageid": 1, "title": "Ada Lovelace", "lastrevid": checks, "touched": "2026-10-19T10:00:00Z"},
+		}}}
+	})
+
+	changes := make(chan watcher.Change, 100)
+	errs := make(chan error, 100)
+	w, err := watcher.New(watcher.Options{Interval: time.Millisecond, OnError: func(err error) { errs <- err }}, func(c watcher.Change) {
+		changes <- c
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	w.Add("Ada Lovelace")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	// Read pages while the watcher invalidates their cache
+	deadline := time.After(5 * time.Second)
+	seen, failed := 0, 0
+	for i := 0; seen < 3 || failed < 1; i++ {
+		utils.RequestWikiApi(map[string]string{"prop": "extracts", "titles": "Ada Lovelace"})
+		utils.RequestWikiApi(map[string]string{"prop": "extracts", "titles": "Page " + strconv.Itoa(i%10)})
+		select {
+		case <-changes:
+			seen++
+		case <-errs:
+			failed++
+		case <-deadline:
+			t.Fatalf("got %v changes and %v errors before the deadline", seen, failed)
+		default:
+		}
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, expect the context error", err)
+	}
+}
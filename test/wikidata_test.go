@@ -0,0 +1,167 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+	"github.com/trietmn/go-wiki/wikidata"
+)
+
+const mockEntity = `{"entities": {"Q7259": {
+	"type": "item", "id": "Q7259",
+	"labels": {"en": {"language": "en", "value": "Ada Lovelace"}, "fr": {"language": "fr", "value": "Ada Lovelace"}},
+	"descriptions": {"en": {"language": "en", "value": "English mathematician (1815–1852)"}},
+	"aliases": {"en": [{"language": "en", "value": "Augusta Ada King"}, {"language": "en", "value": "Countess of Lovelace"}]},
+	"sitelinks": {"enwiki": {"site": "enwiki", "title": "Ada Lovelace", "badges": ["Q17437796"], "url": "https://en.wikipedia.org/wiki/Ada_Lovelace"}},
+	"claims": {
+		"P31": [{"id": "Q7259$1", "rank": "normal", "type": "statement", "mainsnak": {"snaktype": "value", "property": "P31", "datatype": "wikibase-item",
+			"datavalue": {"type": "wikibase-entityid", "value": {"entity-type": "item", "numeric-id": 5, "id": "Q5"}}}}],
+		"P569": [{"id": "Q7259$2", "rank": "normal", "type": "statement", "mainsnak": {"snaktype": "value", "property": "P569", "datatype": "time",
+			"datavalue": {"type": "time", "value": {"time": "+1815-12-10T00:00:00Z", "timezone": 0, "before": 0, "after": 0, "precision": 11, "calendarmodel": "http://www.wikidata.org/entity/Q1985727"}}}}],
+		"P2048": [
+			{"id": "Q7259$3", "rank": "deprecated", "type": "statement", "mainsnak": {"snaktype": "value", "property": "P2048", "datatype": "quantity",
+				"datavalue": {"type": "quantity", "value": {"amount": "+1.50", "unit": "http://www.wikidata.org/entity/Q11573"}}}},
+			{"id": "Q7259$4", "rank": "normal", "type": "statement", "mainsnak": {"snaktype": "value", "property": "P2048", "datatype": "quantity",
+				"datavalue": {"type": "quantity", "value": {"amount": "+1.65", "unit": "http://www.wikidata.org/entity/Q11573", "upperBound": "+1.70", "lowerBound": "+1.60"}}}}
+		],
+		"P625": [{"id": "Q7259$5", "rank": "preferred", "type": "statement", "mainsnak": {"snaktype": "value", "property": "P625", "datatype": "globe-coordinate",
+			"datavalue": {"type": "globecoordinate", "value": {"latitude": 51.5, "longitude": -0.12, "precision": 0.01, "globe": "http://www.wikidata.org/entity/Q2"}}}}],
+		"P214": [{"id": "Q7259$6", "rank": "normal", "type": "statement", "mainsnak": {"snaktype": "value", "property": "P214", "datatype": "external-id",
+			"datavalue": {"type": "string", "value": "37040386"}},
+			"qualifiers": {"P1810": [{"snaktype": "value", "property": "P1810", "datatype": "string", "datavalue": {"type": "string", "value": "Lovelace, Ada"}}]}}],
+		"P570": [{"id": "Q7259$7", "rank": "normal", "type": "statement", "mainsnak": {"snaktype": "somevalue", "property": "P570", "datatype": "time"}}]
+	}
+}}, "success": 1}`
+
+func mockWikibase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("action") != "wbgetentities" || q.Get("ids") != "Q7259" {
+			w.Write([]byte(`{"error": {"code": "no-such-entity", "info": "Could not find an entity with the ID \"` + q.Get("ids") + `\"."}}`))
+			return
+		}
+		w.Write([]byte(mockEntity))
+	}))
+	old := wikidata.APIURL
+	wikidata.APIURL = server.URL + "/w/api.php"
+	t.Cleanup(func() {
+		server.Close()
+		wikidata.APIURL = old
+	})
+}
+
+func TestGetWikidataID(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	id, err := p.GetWikidataID()
+	if err != nil || id != "Q7259" {
+		t.Errorf("got %v %v, expect Q7259", id, err)
+	}
+}
+
+func TestGetWikidataEntity(t *testing.T) {
+	utils.WikiRequester = MockRequester
+	mockWikibase(t)
+	p := page.WikipediaPage{Title: "Ada Lovelace", PageID: 974}
+	entity, err := p.GetWikidataEntity()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if entity.Label("en") != "Ada Lovelace" || entity.Description("en") != "English mathematician (1815–1852)" || len(entity.Aliases["en"]) != 2 {
+		t.Errorf("unexpected terms %+v", entity)
+	}
+	if entity.Sitelinks["enwiki"].URL != "https://en.wikipedia.org/wiki/Ada_Lovelace" {
+		t.Errorf("unexpected sitelinks %+v", entity.Sitelinks)
+	}
+
+	if v, ok := entity.Value("P31"); !ok || v.Kind != wikidata.KindItem || v.Item != "Q5" {
+		t.Errorf("unexpected item %+v", v)
+	}
+	v, _ := entity.Value("P569")
+	if v.Kind != wikidata.KindTime || !v.Time.Time.Equal(time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC)) || v.Time.Precision != wikidata.PrecisionDay || v.Time.Calendar != "Q1985727" {
+		t.Errorf("unexpected time %+v", v)
+	}
+	// The deprecated statement is skipped
+	v, _ = entity.Value("P2048")
+	if v.Kind != wikidata.KindQuantity || v.Quantity.Amount != 1.65 || v.Quantity.Unit != "Q11573" || *v.Quantity.UpperBound != 1.70 {
+		t.Errorf("unexpected quantity %+v", v)
+	}
+	v, _ = entity.Value("P625")
+	if v.Kind != wikidata.KindCoordinate || v.Coordinate.Latitude != 51.5 || v.Coordinate.Longitude != -0.12 || v.Coordinate.Globe != "Q2" {
+		t.Errorf("unexpected coordinate %+v", v)
+	}
+	claims := entity.BestClaims("P214")
+	if len(claims) != 1 || claims[0].Value.String != "37040386" || claims[0].Value.DataType != "external-id" || claims[0].Qualifiers["P1810"][0].String != "Lovelace, Ada" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if v, _ := entity.Value("P570"); v.Kind != wikidata.KindSomeValue {
+		t.Errorf("unexpected value %+v", v)
+	}
+}
+
+func TestWikidataErrors(t *testing.T) {
+	mockWikibase(t)
+	_, err := wikidata.GetEntity("Q0")
+	if err == nil || err.Error() != `Could not find an entity with the ID "Q0".` {
+		t.Errorf("unexpected error %v", err)
+	}
+	if d := wikidata.ParseTime("+1815-00-00T00:00:00Z"); !d.Equal(time.Date(1815, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %v", d)
+	}
+	// 44 BCE is the astronomical year -43
+	if d := wikidata.ParseTime("-0044-03-15T00:00:00Z"); !d.Equal(time.Date(-43, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %v", d)
+	}
+}
+
+func TestWikidataIDLoadedWithPage(t *testing.T) {
+	requests := []url.Values{}
+	MockAPIServer(t, func(q url.Values) interface{} {
+		requests = append(requests, q)
+		return map[string]interface{}{"query": map[string]interface{}{"pages": map[string]interface{}{
+			"974": map[string]interface{}{"pageid": 974, "ns": 0, "title": "Ada Lovelace", "pageprops": map[string]string{"wikibase_item": "Q7259"}},
+		}}}
+	})
+	p, err := page.MakeWikipediaPage(-1, "Ada Lovelace", "", true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if requests[0].Get("ppprop") != "disambiguation|wikibase_item" || p.WikidataID != "Q7259" {
+		t.Errorf("got %v with %v, expect Q7259", p.WikidataID, requests[0])
+	}
+	// No other request is needed
+	if id, err := p.GetWikidataID(); err != nil || id != "Q7259" || len(requests) != 1 {
+		t.Errorf("got %v %v after %v requests", id, err, len(requests))
+	}
+}
+
+func TestWikidataErrorsNotCached(t *testing.T) {
+	utils.Cache.Clear()
+	defer utils.Cache.Clear()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte(`{"error": {"code": "maxlag", "info": "Waiting for a database server."}}`))
+			return
+		}
+		w.Write([]byte(mockEntity))
+	}))
+	defer server.Close()
+	old := wikidata.APIURL
+	wikidata.APIURL = server.URL + "/w/api.php"
+	defer func() { wikidata.APIURL = old }()
+
+	if _, err := wikidata.GetEntity("Q7259"); err == nil {
+		t.Errorf("expect the error of the first response")
+	}
+	entity, err := wikidata.GetEntity("Q7259")
+	if err != nil || entity.ID != "Q7259" || calls != 2 {
+		t.Errorf("got %+v %v after %v calls, expect the entity from a new request", entity.ID, err, calls)
+	}
+}
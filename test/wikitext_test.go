@@ -0,0 +1,160 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/trietmn/go-wiki/wikitext"
+)
+
+const sampleWikitext = `{{Infobox person
+| name        = Ada Lovelace
+| birth_date  = {{birth date|1815|12|10}}
+| occupation  = [[Mathematician]], [[writer|Writer]]
+}}
+'''Augusta Ada King''' was an English [[mathematician]].<ref name="bbc">{{cite web |url=https://www.bbc.co.uk/ada |title=Ada}}</ref> See [https://example.org the site].<!-- hidden -->
+
+== Early life ==
+She was born in London.<ref name="bbc" />
+
+=== Education ===
+* Tutored by [[Mary Somerville]]
+*# Nested item
+# Numbered
+{| class="wikitable"
+|+ Works
+! Year !! Title
+|-
+| 1843 || style="color:red" | Notes
+|-
+| 1844 || [[Analytical Engine|Engine]]
+|}
+Visit https://www.findingada.com.
+`
+
+func TestWikitextRoundTrip(t *testing.T) {
+	nodes := wikitext.Parse(sampleWikitext)
+	if nodes.String() != sampleWikitext {
+		t.Errorf("round trip failed, got %v", nodes.String())
+	}
+	for _, broken := range []string{"{{unclosed [[link | <ref>x == y ==\n{|\n| a\n", "a <!-- b"} {
+		if res := wikitext.Parse(broken).String(); res != broken {
+			t.Errorf("got %v, expect %v", res, broken)
+		}
+	}
+}
+
+func TestWikitextTemplates(t *testing.T) {
+	nodes := wikitext.Parse(sampleWikitext)
+	infobox := nodes.TemplatesNamed("infobox_person")
+	if len(infobox) != 1 {
+		t.Fatalf("got %v infobox, expect 1", len(infobox))
+	}
+	if name := infobox[0].Get("name").Text(); name != "Ada Lovelace" {
+		t.Errorf("got %v, expect Ada Lovelace", name)
+	}
+	birth := infobox[0].Get("birth_date").Value.Templates()
+	if len(birth) != 1 || birth[0].Get("1").Text() != "1815" || birth[0].Get("3").Text() != "10" {
+		t.Errorf("unexpected birth date template %v", birth)
+	}
+	infobox[0].Set("name", "Ada King")
+	infobox[0].Remove("occupation")
+	infobox[0].Set("spouse", "William King")
+	expect := "{{Infobox person\n| name        = Ada King\n| birth_date  = {{birth date|1815|12|10}}\n|spouse=William King}}"
+	if res := infobox[0].String(); res != expect {
+		t.Errorf("got %q, expect %q", res, expect)
+	}
+}
+
+func TestWikitextStructure(t *testing.T) {
+	nodes := wikitext.Parse(sampleWikitext)
+	headings := nodes.Headings()
+	if len(headings) != 2 || headings[0].Level != 2 || headings[1].Text() != "Education" || headings[1].Level != 3 {
+		t.Errorf("unexpected headings %v", headings)
+	}
+	links := []string{}
+	for _, l := range nodes.Wikilinks() {
+		links = append(links, l.Title()+"|"+l.Text())
+	}
+	expect := []string{"Mathematician|Mathematician", "Writer|Writer", "Mathematician|mathematician", "Mary Somerville|Mary Somerville", "Analytical Engine|Engine"}
+	if len(links) != len(expect) {
+		t.Fatalf("got %v, expect %v", links, expect)
+	}
+	for i := range expect {
+		if links[i] != expect[i] {
+			t.Errorf("got %v, expect %v", links[i], expect[i])
+		}
+	}
+	refs := nodes.Refs()
+	if len(refs) != 2 || !refs[1].SelfClosing {
+		t.Fatalf("unexpected refs %v", refs)
+	}
+	if name, _ := refs[0].Attr("name"); name != "bbc" {
+		t.Errorf("got %v, expect bbc", name)
+	}
+	ext := nodes.ExternalLinks()
+	if len(ext) != 3 || ext[1].URL != "https://example.org" || ext[1].Text() != "the site" || ext[2].URL != "https://www.findingada.com" {
+		t.Errorf("unexpected external links %v", ext)
+	}
+	if len(nodes.Comments()) != 1 {
+		t.Errorf("expect 1 comment")
+	}
+
+	var list *wikitext.List
+	for _, n := range nodes {
+		if l, ok := n.(*wikitext.List); ok {
+			list = l
+		}
+	}
+	if list == nil || len(list.Items) != 3 || list.Items[1].Marker != "*#" {
+		t.Errorf("unexpected list %v", list)
+	}
+
+	tables := nodes.Tables()
+	if len(tables) != 1 {
+		t.Fatalf("got %v tables, expect 1", len(tables))
+	}
+	table := tables[0]
+	if table.Caption == nil || table.Caption.Text() != "Works" {
+		t.Errorf("unexpected caption %v", table.Caption)
+	}
+	if len(table.Rows) != 3 || len(table.Rows[0].Cells) != 2 || !table.Rows[0].Cells[0].Header {
+		t.Fatalf("unexpected rows %v", table.Rows)
+	}
+	cell := table.Rows[1].Cells[1]
+	if cell.Text() != "Notes" || wikitext.ParseAttrs(cell.Attrs)["style"] != "color:red" {
+		t.Errorf("unexpected cell %v", cell)
+	}
+	if res := table.Rows[2].Cells[1]; res.Attrs != "" || res.Text() != "Engine" {
+		t.Errorf("got %v, expect Engine without attributes", res)
+	}
+}
+
+func TestWikitextPlainText(t *testing.T) {
+	nodes := wikitext.Parse("'''Ada''' was a [[mathematician|math person]].<ref>x</ref><!-- c -->{{citation needed}} [[File:Ada.jpg|thumb]]")
+	expect := "Ada was a math person. "
+	if res := nodes.Text(); res != expect {
+		t.Errorf("got %q, expect %q", res, expect)
+	}
+}
+
+func TestWikitextClosingTags(t *testing.T) {
+	src := "a<REF name=x>b</ref >c<nowiki>[[d]]</refs></NOWIKI\n>e<ref>f</refx>"
+	nodes := wikitext.Parse(src)
+	if nodes.String() != src {
+		t.Errorf("round trip failed, got %v", nodes.String())
+	}
+	tags := nodes.Tags("")
+	if len(tags) != 3 {
+		t.Fatalf("got %v tags, expect 3", len(tags))
+	}
+	if tags[0].Close != "</ref >" || tags[0].Contents.String() != "b" {
+		t.Errorf("unexpected tag %+v", tags[0])
+	}
+	if tags[1].Close != "</NOWIKI\n>" || tags[1].Contents.String() != "[[d]]</refs>" || len(tags[1].Contents.Wikilinks()) != 0 {
+		t.Errorf("unexpected raw tag %+v", tags[1])
+	}
+	// </refx> does not close the ref
+	if tags[2].Close != "" || tags[2].Contents != nil {
+		t.Errorf("unexpected unclosed tag %+v", tags[2])
+	}
+}
@@ -0,0 +1,272 @@
+package gowiki
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/auth"
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Files larger than this are uploaded in chunks by default
+const DefaultChunkSize int64 = 5 << 20
+
+// Time between 2 checks of a file published in the background
+const uploadPollInterval = time.Second
+
+// Options of UploadFile
+type UploadOptions struct {
+	Comment        string // Upload summary. Use "" for the default of the API
+	IgnoreWarnings bool   // Upload even if the file is a duplicate, or replaces an existing file
+	Watchlist      string // Any of the page.Watchlist constants. Use "" for the default of the API
+	// Files larger than this are uploaded in chunks of this size. Use 0 for DefaultChunkSize
+	ChunkSize int64
+	// Called after each chunk with the number of bytes sent and the size of the file
+	Progress func(sent int64, total int64)
+}
+
+// A successful upload
+type UploadResult struct {
+	Filename       string   `json:"filename"` // Name of the file, without the "File:" prefix
+	URL            string   `json:"url"`
+	DescriptionURL string   `json:"descriptionurl"`
+	Size           int      `json:"size"`
+	MIME           string   `json:"mime"`
+	SHA1           string   `json:"sha1"`
+	Warnings       []string `json:"warnings"` // Warnings of the API, ignored with UploadOptions.IgnoreWarnings
+}
+
+// The upload has been stopped by warnings. Set UploadOptions.IgnoreWarnings to upload anyway
+type UploadWarningError struct {
+	*utils.APIError
+	Warnings   map[string]interface{} // Warnings of the API by code, ex: "exists", "duplicate", "was-deleted"
+	Duplicates []string               // Existing files with the same content
+	FileKey    string                 // Key of the file in the stash of the user
+}
+
+func (err *UploadWarningError) Unwrap() error { return err.APIError }
+
+/*
+Upload a file with action=upload, using the activated auth session. The file is sent in chunks,
+through the upload stash, when it is larger than the chunk size.
+
+Keyword arguments:
+
+* filename: Name of the file on the wiki, ex: "Ada_Lovelace_portrait.jpg"
+
+* reader: Content of the file
+
+* description: Wikitext of the description page
+
+* license: License template added to the description page, ex: "cc-by-sa-4.0". Use "" if the description has one
+
+* opts: Warnings, chunks and progress options
+
+Return:
+
+* The uploaded file
+
+* Error: *UploadWarningError for the duplicates and the existing files, or the typed errors of utils.NewAPIError
+*/
+func UploadFile(filename string, reader io.Reader, description string, license string, opts UploadOptions) (UploadResult, error) {
+	session := auth.Default
+	if session == nil {
+		return UploadResult{}, auth.ErrNoSession
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	args := map[string]string{
+		"action":   "upload",
+		"filename": filename,
+		"text":     uploadText(description, license),
+	}
+	if opts.Comment != "" {
+		args["comment"] = opts.Comment
+	}
+	if opts.IgnoreWarnings {
+		args["ignorewarnings"] = "1"
+	}
+	if opts.Watchlist != "" {
+		args["watchlist"] = opts.Watchlist
+	}
+
+	size := readerSize(reader)
+	first, err := ioutil.ReadAll(io.LimitReader(reader, opts.ChunkSize+1))
+	if err != nil {
+		return UploadResult{}, err
+	}
+	var res models.RequestResult
+	if int64(len(first)) <= opts.ChunkSize {
+		res, err = session.PostFileWithToken(auth.TokenCSRF, args, utils.FormFile{Field: "file", Filename: filename, Content: first})
+		if err != nil {
+			return UploadResult{}, err
+		}
+		if opts.Progress != nil && res.Error.Code == "" {
+			opts.Progress(int64(len(first)), int64(len(first)))
+		}
+	} else {
+		rest := io.MultiReader(bytes.NewReader(first), reader)
+		if size < 0 {
+			// The API needs the size of the file before the first chunk
+			spool, err := ioutil.TempFile("", "gowiki-upload-*")
+			if err != nil {
+				return UploadResult{}, err
+			}
+			defer os.Remove(spool.Name())
+			defer spool.Close()
+			if size, err = io.Copy(spool, rest); err != nil {
+				return UploadResult{}, err
+			}
+			if _, err := spool.Seek(0, io.SeekStart); err != nil {
+				return UploadResult{}, err
+			}
+			rest = spool
+		}
+		filekey, err := uploadChunks(session, filename, rest, size, opts)
+		if err != nil {
+			return UploadResult{}, err
+		}
+		// Publish the stashed file. A large file takes longer than a request to publish, so it is published in the background
+		args["filekey"] = filekey
+		args["async"] = "1"
+		res, err = session.PostWithToken(auth.TokenCSRF, args)
+		if err != nil {
+			return UploadResult{}, err
+		}
+		for res.Error.Code == "" && res.Upload.Result == "Poll" {
+			time.Sleep(uploadPollInterval)
+			res, err = session.PostWithToken(auth.TokenCSRF, map[string]string{"action": "upload", "checkstatus": "1", "filekey": filekey})
+			if err != nil {
+				return UploadResult{}, err
+			}
+		}
+	}
+	if err := uploadError(res); err != nil {
+		return UploadResult{}, err
+	}
+	// The cached requests about the file are outdated
+	utils.Cache.InvalidateTitle("File:" + filename)
+	info := res.Upload.ImageInfo
+	return UploadResult{
+		Filename:       res.Upload.Filename,
+		URL:            info.URL,
+		DescriptionURL: info.DescriptionURL,
+		Size:           info.Size,
+		MIME:           info.Mime,
+		SHA1:           info.SHA1,
+		Warnings:       warningCodes(res.Upload.Warnings),
+	}, nil
+}
+
+// Send the file to the upload stash in chunks, and return its file key
+func uploadChunks(session *auth.Session, filename string, reader io.Reader, size int64, opts UploadOptions) (string, error) {
+	buf := make([]byte, opts.ChunkSize)
+	filekey := ""
+	var offset int64
+	for offset < size {
+		n, err := io.ReadFull(reader, buf)
+		if err == io.EOF {
+			return "", errors.New("the file is shorter than its size")
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		args := map[string]string{
+			"action":   "upload",
+			"stash":    "1",
+			"filename": filename,
+			"filesize": strconv.FormatInt(size, 10),
+			"offset":   strconv.FormatInt(offset, 10),
+		}
+		if filekey != "" {
+			args["filekey"] = filekey
+		}
+		if opts.IgnoreWarnings {
+			args["ignorewarnings"] = "1"
+		}
+		res, err := session.PostFileWithToken(auth.TokenCSRF, args, utils.FormFile{Field: "chunk", Filename: filename, Content: buf[:n]})
+		if err != nil {
+			return "", err
+		}
+		if err := uploadError(res); err != nil {
+			return "", err
+		}
+		filekey = res.Upload.FileKey
+		offset += int64(n)
+		if opts.Progress != nil {
+			opts.Progress(offset, size)
+		}
+	}
+	return filekey, nil
+}
+
+// Typed error of an upload result, nil for a successful upload or chunk
+func uploadError(res models.RequestResult) error {
+	if err := utils.ResultError(res); err != nil {
+		return err
+	}
+	switch res.Upload.Result {
+	case "Success", "Continue":
+		return nil
+	case "Warning":
+		codes := warningCodes(res.Upload.Warnings)
+		err := &UploadWarningError{
+			APIError: &utils.APIError{Code: "uploadwarning", Info: "the upload has warnings: " + strings.Join(codes, ", ")},
+			Warnings: res.Upload.Warnings,
+			FileKey:  res.Upload.FileKey,
+		}
+		if duplicates, ok := res.Upload.Warnings["duplicate"].([]interface{}); ok {
+			for _, d := range duplicates {
+				err.Duplicates = append(err.Duplicates, fmt.Sprint(d))
+			}
+		}
+		return err
+	}
+	return fmt.Errorf("unexpected upload result %q", res.Upload.Result)
+}
+
+func warningCodes(warnings map[string]interface{}) []string {
+	codes := make([]string, 0, len(warnings))
+	for code := range warnings {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Text of the description page of an upload
+func uploadText(description string, license string) string {
+	if license == "" {
+		return description
+	}
+	return fmt.Sprintf("%v\n\n== {{int:license-header}} ==\n{{%v}}", description, license)
+}
+
+// Size of the content of a reader, or -1 if it is unknown
+func readerSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		stat, err := r.Stat()
+		if err != nil || !stat.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return stat.Size() - offset
+	}
+	return -1
+}
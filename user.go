@@ -0,0 +1,295 @@
+package gowiki
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/page"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+// Max number of users in a single list=users request
+const usersBatchSize = 50
+
+// A registered user
+type User struct {
+	UserID       int        `json:"userid"`
+	Name         string     `json:"name"`
+	EditCount    int        `json:"editcount"`
+	Registration time.Time  `json:"registration"` // Zero for the very old accounts
+	Groups       []string   `json:"groups"`       // Implicit groups included, ex: "*", "user", "autoconfirmed"
+	Gender       string     `json:"gender"`       // "male", "female" or "unknown"
+	Block        *UserBlock `json:"block"`        // nil if the user is not blocked
+}
+
+// The block of a user
+type UserBlock struct {
+	ID        int       `json:"id"`
+	By        string    `json:"by"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+	Expiry    time.Time `json:"expiry"`  // Zero when the block is infinite
+	Partial   bool      `json:"partial"` // The block only applies to some pages or namespaces
+}
+
+// Filters of GetUserContribs
+type UserContribsOptions struct {
+	Namespaces  []int     // Empty for all
+	Since       time.Time // Only the contributions made at or after this time. Zero for no limit
+	Until       time.Time // Only the contributions made at or before this time. Zero for no limit
+	OldestFirst bool      // List the oldest contributions first, instead of the newest
+	Limit       int       // Max number of contributions. Use 0 for all of them
+}
+
+// A contribution of a user
+type UserContrib struct {
+	User      string    `json:"user"`
+	PageID    int       `json:"pageid"`
+	RevID     int       `json:"revid"`
+	ParentID  int       `json:"parentid"`
+	Ns        int       `json:"ns"`
+	Title     string    `json:"title"`
+	Timestamp time.Time `json:"timestamp"`
+	Comment   string    `json:"comment"`
+	Size      int       `json:"size"`
+	SizeDelta int       `json:"sizedelta"`
+	New       bool      `json:"new"` // The revision has created the page
+	Minor     bool      `json:"minor"`
+	Top       bool      `json:"top"` // The revision is the current one of the page
+	Tags      []string  `json:"tags"`
+}
+
+// Iterate over the contributions of a user, fetching them from the API one batch at a time
+type UserContribsStream struct {
+	args     map[string]string
+	limit    int
+	returned int
+	last     map[string]interface{} // Continuation of the next request, nil when every batch is fetched
+	buffer   []UserContrib
+	err      error
+	lock     sync.Mutex
+}
+
+/*
+Get a user with list=users: edit count, registration, groups, block and gender.
+
+Returns a *utils.MissingPageError if the user does not exist
+*/
+func GetUser(name string) (User, error) {
+	users, err := GetUsers([]string{name})
+	if err != nil {
+		return User{}, err
+	}
+	if len(users) == 0 {
+		return User{}, utils.NewAPIError("nosuchuser", "the user does not exist")
+	}
+	return users[0], nil
+}
+
+/*
+Get several users at once, in the order of the names. The missing users are skipped
+*/
+func GetUsers(names []string) ([]User, error) {
+	users := make([]User, 0, len(names))
+	for start := 0; start < len(names); start += usersBatchSize {
+		end := start + usersBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		args := map[string]string{
+			"action":  "query",
+			"list":    "users",
+			"ususers": strings.Join(names[start:end], "|"),
+			"usprop":  "editcount|registration|groups|blockinfo|gender",
+		}
+		res, err := utils.WikiRequester(args)
+		if err != nil {
+			return []User{}, err
+		}
+		if res.Error.Code != "" {
+			return []User{}, errors.New(res.Error.Info)
+		}
+		for _, v := range res.Query.Users {
+			if v.Missing == nil && v.Invalid == nil {
+				users = append(users, makeUser(v))
+			}
+		}
+	}
+	return users, nil
+}
+
+func makeUser(v models.InnerUser) User {
+	user := User{
+		UserID:       v.UserID,
+		Name:         v.Name,
+		EditCount:    v.EditCount,
+		Registration: parseAPITime(v.Registration),
+		Groups:       v.Groups,
+		Gender:       v.Gender,
+	}
+	if v.BlockID != 0 {
+		user.Block = &UserBlock{
+			ID:        v.BlockID,
+			By:        v.BlockedBy,
+			Reason:    v.BlockReason,
+			Timestamp: parseAPITime(v.BlockedTimestamp),
+			Expiry:    parseAPITime(v.BlockExpiry),
+			Partial:   v.BlockPartial != nil,
+		}
+	}
+	return user
+}
+
+// Parse an API timestamp. Returns the zero time for empty or "infinity" values
+func parseAPITime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+/*
+Stream the contributions of a user with list=usercontribs. The requests are only made when
+the contributions are read, use Next to iterate over them, or Contribs to get them on a channel.
+
+Keyword arguments:
+
+* name: Name of the user, or an IP address
+
+* opts: Namespace and time filters
+*/
+func GetUserContribs(name string, opts UserContribsOptions) *UserContribsStream {
+	args := map[string]string{
+		"action":  "query",
+		"list":    "usercontribs",
+		"ucuser":  name,
+		"ucprop":  "ids|title|timestamp|comment|size|sizediff|flags|tags",
+		"uclimit": "max",
+		// The contributions change all the time, the cache would hide the latest ones
+		utils.NoCacheArg: "",
+	}
+	if len(opts.Namespaces) > 0 {
+		args["ucnamespace"] = page.JoinNamespaces(opts.Namespaces)
+	}
+	// ucstart is where the listing starts, so it is the newest time unless the oldest come first
+	start, end := opts.Until, opts.Since
+	if opts.OldestFirst {
+		args["ucdir"] = "newer"
+		start, end = opts.Since, opts.Until
+	}
+	if !start.IsZero() {
+		args["ucstart"] = start.UTC().Format(time.RFC3339)
+	}
+	if !end.IsZero() {
+		args["ucend"] = end.UTC().Format(time.RFC3339)
+	}
+	if opts.Limit > 0 && opts.Limit < 500 {
+		args["uclimit"] = strconv.Itoa(opts.Limit)
+	}
+	return &UserContribsStream{args: args, limit: opts.Limit, last: map[string]interface{}{}}
+}
+
+/*
+Return the next contribution, fetching a new batch when needed.
+
+Returns io.EOF after the last contribution
+*/
+func (stream *UserContribsStream) Next() (UserContrib, error) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	if stream.limit > 0 && stream.returned >= stream.limit {
+		return UserContrib{}, io.EOF
+	}
+	for len(stream.buffer) == 0 {
+		if stream.err != nil {
+			return UserContrib{}, stream.err
+		}
+		if stream.last == nil {
+			return UserContrib{}, io.EOF
+		}
+		stream.err = stream.fetch()
+	}
+	contrib := stream.buffer[0]
+	stream.buffer = stream.buffer[1:]
+	stream.returned++
+	return contrib, nil
+}
+
+// Fetch the next batch of contributions into the buffer
+func (stream *UserContribsStream) fetch() error {
+	args := utils.CopyMap(stream.args)
+	utils.UpdateMap(args, stream.last)
+	res, err := utils.WikiRequester(args)
+	if err != nil {
+		return err
+	}
+	if res.Error.Code != "" {
+		return errors.New(res.Error.Info)
+	}
+	for _, v := range res.Query.UserContribs {
+		stream.buffer = append(stream.buffer, makeUserContrib(v))
+	}
+	stream.last = nil
+	if len(res.Continue) > 0 {
+		stream.last = res.Continue
+	}
+	return nil
+}
+
+/*
+Return the error that closed the channel of Contribs, if any
+*/
+func (stream *UserContribsStream) Err() error {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	return stream.err
+}
+
+/*
+Deliver the contributions on a channel, closed after the last one, when the context is done
+or when a request fails, see Err
+*/
+func (stream *UserContribsStream) Contribs(ctx context.Context) <-chan UserContrib {
+	ch := make(chan UserContrib)
+	go func() {
+		defer close(ch)
+		for {
+			contrib, err := stream.Next()
+			if err != nil {
+				return
+			}
+			select {
+			case ch <- contrib:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func makeUserContrib(v models.InnerUserContrib) UserContrib {
+	return UserContrib{
+		User:      v.User,
+		PageID:    v.PageID,
+		RevID:     v.RevID,
+		ParentID:  v.ParentID,
+		Ns:        v.Ns,
+		Title:     v.Title,
+		Timestamp: parseAPITime(v.Timestamp),
+		Comment:   v.Comment,
+		Size:      v.Size,
+		SizeDelta: v.SizeDiff,
+		New:       v.New != nil,
+		Minor:     v.Minor != nil,
+		Top:       v.Top != nil,
+		Tags:      v.Tags,
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/trietmn/go-wiki/models"
+)
+
+// Error codes of the API for each kind of error
+var (
+	editConflictCodes = []string{"editconflict", "pagedeleted"}
+	protectedCodes    = []string{"protectedpage", "cascadeprotected", "protectedtitle", "protectednamespace", "protectednamespace-interface", "customcssprotected", "customjsprotected", "customjsonprotected"}
+	pageExistsCodes   = []string{"articleexists", "selfmove", "fileexists-no-change"}
+	missingPageCodes  = []string{"missingtitle", "nocreate-missing", "cantundelete", "nosuchpageid", "nosuchrevid", "nosuchuser"}
+	permissionCodes   = []string{"permissiondenied", "badaccess-groups", "writeapidenied", "cantcreate", "cantcreate-anon", "blocked", "autoblocked", "readonly", "ratelimited"}
+)
+
+// Error returned by the API. Every typed error of the library wraps one, so `errors.As(err, &apiErr)` gets the code of any of them
+type APIError struct {
+	Code string // Error code, ex: "editconflict"
+	Info string // Message of the API
+}
+
+func (err *APIError) Error() string {
+	if err.Info == "" {
+		return err.Code
+	}
+	return err.Info
+}
+
+// The page has been changed, or deleted, since the edit started
+type EditConflictError struct{ *APIError }
+
+func (err *EditConflictError) Unwrap() error { return err.APIError }
+
+// The page is protected against the action, or cannot be created
+type ProtectedPageError struct{ *APIError }
+
+func (err *ProtectedPageError) Unwrap() error { return err.APIError }
+
+// The action has been disallowed, or warned about, by an abuse filter
+type AbuseFilterError struct {
+	*APIError
+	FilterID    string   // ID of the filter, ex: "12" or "global-3"
+	Description string   // Description of the filter
+	Actions     []string // Actions of the filter, ex: "disallow", "warn"
+}
+
+func (err *AbuseFilterError) Unwrap() error { return err.APIError }
+
+// The target page already exists, ex: when creating a page or moving to an existing title
+type PageExistsError struct{ *APIError }
+
+func (err *PageExistsError) Unwrap() error { return err.APIError }
+
+// The page, the revision or the user does not exist
+type MissingPageError struct{ *APIError }
+
+func (err *MissingPageError) Unwrap() error { return err.APIError }
+
+// The user is not allowed to make the action, or is blocked or rate limited
+type PermissionError struct{ *APIError }
+
+func (err *PermissionError) Unwrap() error { return err.APIError }
+
+/*
+Make the typed error of an API error code, ex: an *EditConflictError for "editconflict".
+The unknown codes give a plain *APIError
+*/
+func NewAPIError(code string, info string) error {
+	base := &APIError{Code: code, Info: info}
+	switch {
+	case Isin(editConflictCodes, code):
+		return &EditConflictError{base}
+	case Isin(protectedCodes, code):
+		return &ProtectedPageError{base}
+	case strings.HasPrefix(code, "abusefilter"):
+		return &AbuseFilterError{APIError: base}
+	case Isin(pageExistsCodes, code):
+		return &PageExistsError{base}
+	case Isin(missingPageCodes, code):
+		return &MissingPageError{base}
+	case Isin(permissionCodes, code):
+		return &PermissionError{base}
+	}
+	return base
+}
+
+/*
+Return the typed error of the error of a result, or nil if the request succeeded
+*/
+func ResultError(res models.RequestResult) error {
+	if res.Error.Code == "" {
+		return nil
+	}
+	err := NewAPIError(res.Error.Code, res.Error.Info)
+	if filter, ok := err.(*AbuseFilterError); ok {
+		if res.Error.AbuseFilter.ID != nil {
+			filter.FilterID = fmt.Sprint(res.Error.AbuseFilter.ID)
+		}
+		filter.Description = res.Error.AbuseFilter.Description
+		filter.Actions = res.Error.AbuseFilter.Actions
+	}
+	return err
+}
@@ -1,12 +1,18 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	neturl "net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/trietmn/go-wiki/cache"
@@ -16,6 +22,10 @@ import (
 const (
 	ReqPerSec = 199
 	ApiGap    = time.Second / ReqPerSec
+	// Request argument overriding WikiLanguage for a single request. It is not sent to the API
+	LangArg = "_lang"
+	// Request argument skipping the cache, for the requests polling for changes. It is not sent to the API
+	NoCacheArg = "_nocache"
 )
 
 var (
@@ -25,6 +35,8 @@ var (
 	LastCall      time.Time       = time.Now()
 	Cache         cache.WikiCache = cache.MakeWikiCache()
 	WikiRequester                 = RequestWikiApi
+	HTTPClient    *http.Client    = &http.Client{Timeout: 10 * time.Second} // Client used for every API request
+	lastCallLock  sync.Mutex
 )
 
 func TurnSliceOfString(s []interface{}) []string {
@@ -66,23 +78,64 @@ func Isin(list []string, s string) bool {
 Update the last time we call the API (API should)
 */
 func UpdateLastCall(now time.Time) {
+	lastCallLock.Lock()
+	defer lastCallLock.Unlock()
 	LastCall = now
 }
 
+/*
+Block until a new request can be made without going over ReqPerSec requests per second.
+Safe to call from multiple goroutines
+*/
+func WaitRateLimit() {
+	lastCallLock.Lock()
+	defer lastCallLock.Unlock()
+	now := time.Now()
+	if now.Sub(LastCall) < ApiGap {
+		time.Sleep(LastCall.Add(ApiGap).Sub(now))
+		now = time.Now()
+	}
+	LastCall = now
+}
+
+/*
+Copy of `client` without its total timeout, for the transfers of large files whose body
+takes longer than the timeout to read. The dial and TLS timeouts of the transport still apply
+*/
+func TransferClient(client *http.Client) *http.Client {
+	c := *client
+	c.Timeout = 0
+	return &c
+}
+
+/*
+Make a new HTTP request with the user-agent of the library
+*/
+func NewRequest(method string, url string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", UserAgent)
+	return request, nil
+}
+
 /*
 Make a request to the Wikipedia API using the given search parameters.
 
 Returns a RequestResult (You can see the model in the models.go file)
 */
 func RequestWikiApi(args map[string]string) (models.RequestResult, error) {
-	url := fmt.Sprintf(WikiURL, WikiLanguage)
+	lang := WikiLanguage
+	if v, ok := args[LangArg]; ok && v != "" {
+		lang = v
+	}
+	url := fmt.Sprintf(WikiURL, lang)
 	// Make new request object
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := NewRequest("GET", url, nil)
 	if err != nil {
 		return models.RequestResult{}, err
 	}
-	// Add header
-	request.Header.Set("User-Agent", UserAgent)
 	q := request.URL.Query()
 	// Add parameters
 	if args["format"] == "" {
@@ -92,26 +145,104 @@ func RequestWikiApi(args map[string]string) (models.RequestResult, error) {
 		args["action"] = "query"
 	}
 	for k, v := range args {
-		q.Add(k, v)
+		if k != LangArg && k != NoCacheArg {
+			q.Add(k, v)
+		}
 	}
 	request.URL.RawQuery = q.Encode()
-	now := time.Now()
-	if now.Sub(LastCall) < ApiGap {
-		wait := LastCall.Add(ApiGap).Sub(now)
-		time.Sleep(wait)
-		now = time.Now()
-	}
 	// Check in cache
 	full_url := request.URL.String()
-	r, err := Cache.Get(full_url)
-	if err == nil {
-		return r, nil
+	_, nocache := args[NoCacheArg]
+	if !nocache {
+		r, err := Cache.Get(full_url)
+		if err == nil {
+			return r, nil
+		}
 	}
 
 	// Make GET request
-	client := http.Client{Timeout: 10 * time.Second}
+	result, err := DoWikiRequest(HTTPClient, request)
+	if err != nil {
+		return models.RequestResult{}, err
+	}
+	if !nocache {
+		Cache.Add(full_url, result)
+		Cache.Tag(full_url, requestTitles(args)...)
+	}
+	return result, nil
+}
+
+/*
+Make a POST request to the Wikipedia API with `args` sent as a form, using `client`.
+The write actions, the login and the tokens need a POST. POST requests are never cached
+*/
+func PostWikiApi(client *http.Client, args map[string]string) (models.RequestResult, error) {
+	lang := WikiLanguage
+	if v, ok := args[LangArg]; ok && v != "" {
+		lang = v
+	}
+	form := neturl.Values{}
+	form.Set("format", "json")
+	for k, v := range args {
+		if k != LangArg && k != NoCacheArg {
+			form.Set(k, v)
+		}
+	}
+	request, err := NewRequest("POST", fmt.Sprintf(WikiURL, lang), strings.NewReader(form.Encode()))
+	if err != nil {
+		return models.RequestResult{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return DoWikiRequest(client, request)
+}
+
+// A file sent in a multipart form
+type FormFile struct {
+	Field    string // Name of the form field, ex: "file" or "chunk"
+	Filename string
+	Content  []byte
+}
+
+/*
+Same as PostWikiApi, but send `args` and `file` as a multipart form, for the uploads
+*/
+func PostMultipartWikiApi(client *http.Client, args map[string]string, file FormFile) (models.RequestResult, error) {
+	lang := WikiLanguage
+	if v, ok := args[LangArg]; ok && v != "" {
+		lang = v
+	}
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("format", "json")
+	for k, v := range args {
+		if k != LangArg && k != NoCacheArg {
+			writer.WriteField(k, v)
+		}
+	}
+	part, err := writer.CreateFormFile(file.Field, file.Filename)
+	if err != nil {
+		return models.RequestResult{}, err
+	}
+	if _, err := part.Write(file.Content); err != nil {
+		return models.RequestResult{}, err
+	}
+	if err := writer.Close(); err != nil {
+		return models.RequestResult{}, err
+	}
+	request, err := NewRequest("POST", fmt.Sprintf(WikiURL, lang), body)
+	if err != nil {
+		return models.RequestResult{}, err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return DoWikiRequest(client, request)
+}
+
+/*
+Send a request to the Wikipedia API with `client` and parse the JSON result
+*/
+func DoWikiRequest(client *http.Client, request *http.Request) (models.RequestResult, error) {
+	WaitRateLimit()
 	res, err := client.Do(request)
-	defer UpdateLastCall(now)
 	if err != nil {
 		return models.RequestResult{}, err
 	}
@@ -119,21 +250,119 @@ func RequestWikiApi(args map[string]string) (models.RequestResult, error) {
 	if res.StatusCode != 200 {
 		return models.RequestResult{}, errors.New("unable to fetch the results")
 	}
-	// Read body
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		return models.RequestResult{}, err
 	}
-	// Parse
 	var result models.RequestResult
-	err = json.Unmarshal([]byte(body), &result)
+	err = json.Unmarshal(body, &result)
 	if err != nil {
 		return models.RequestResult{}, err
 	}
-	Cache.Add(full_url, result)
 	return result, nil
 }
 
+// Error of a request answered with a status other than 200
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (err *StatusError) Error() string {
+	if detail := err.Detail(); detail != "" {
+		return detail
+	}
+	return fmt.Sprintf("unable to fetch the results, status %v", err.StatusCode)
+}
+
+/*
+Message of a JSON error body of the REST APIs: {"title": "Not found.", "detail": "..."}.
+Empty if the body is not such an error
+*/
+func (err *StatusError) Detail() string {
+	problem := struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	}{}
+	if json.Unmarshal(err.Body, &problem) != nil {
+		return ""
+	}
+	if problem.Detail != "" {
+		return problem.Detail
+	}
+	return problem.Title
+}
+
+/*
+Make a GET request to any endpoint outside of api.php, ex: a Wikibase or a REST endpoint,
+and return the response body. The request uses the user-agent, the rate limiter and the cache of the library.
+
+Returns a *StatusError if the status is not 200
+*/
+func RequestRaw(endpoint string, args map[string]string) ([]byte, error) {
+	request, err := NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		q := request.URL.Query()
+		for k, v := range args {
+			q.Add(k, v)
+		}
+		request.URL.RawQuery = q.Encode()
+	}
+	// Check in cache
+	full_url := request.URL.String()
+	if body, err := Cache.GetRaw(full_url); err == nil {
+		return body, nil
+	}
+
+	WaitRateLimit()
+	res, err := HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != 200 {
+		return nil, &StatusError{StatusCode: res.StatusCode, Body: body}
+	}
+	// Some APIs answer their errors with a 200, ex: Wikibase. Don't keep them in the cache
+	var apiError struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &apiError) != nil || apiError.Error == nil {
+		Cache.AddRaw(full_url, body)
+	}
+	return body, nil
+}
+
+/*
+Same as RequestRaw, but decode the JSON response into `target`
+*/
+func RequestJSON(endpoint string, args map[string]string, target interface{}) error {
+	body, err := RequestRaw(endpoint, args)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, target)
+}
+
+// Return the page titles a request is about
+func requestTitles(args map[string]string) []string {
+	titles := []string{}
+	if args["titles"] != "" {
+		titles = append(titles, strings.Split(args["titles"], "|")...)
+	}
+	if args["page"] != "" {
+		titles = append(titles, args["page"])
+	}
+	return titles
+}
+
 /*
 Make a deep copy of a map[string]string
 */
@@ -153,12 +382,40 @@ func UpdateMap(a map[string]string, b map[string]interface{}) {
 		switch t := v.(type) {
 		case int:
 			a[k] = strconv.Itoa(t)
+		case float64:
+			a[k] = strconv.FormatFloat(t, 'f', -1, 64)
 		case string:
 			a[k] = t
 		}
 	}
 }
 
+/*
+Request the API with `args` and follow the continuation until every result is fetched.
+Based on <https://www.mediawiki.org/wiki/API:Continue>
+
+`handle` is called with the result of each request. Return false from it to stop early
+*/
+func ContinueQuery(args map[string]string, handle func(res models.RequestResult) bool) error {
+	last := map[string]interface{}{}
+	for {
+		new_args := CopyMap(args)
+		UpdateMap(new_args, last)
+
+		res, err := WikiRequester(new_args)
+		if err != nil {
+			return err
+		}
+		if res.Error.Code != "" {
+			return errors.New(res.Error.Info)
+		}
+		if !handle(res) || len(res.Continue) == 0 {
+			return nil
+		}
+		last = res.Continue
+	}
+}
+
 func HelpAddURL(s string) string {
 	if s[0:4] == "http" {
 		return s
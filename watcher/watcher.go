@@ -0,0 +1,284 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trietmn/go-wiki/utils"
+)
+
+const (
+	DefaultInterval = time.Minute
+	// Max number of titles in a single query
+	batchSize = 50
+)
+
+// Options of a Watcher
+type Options struct {
+	Interval  time.Duration // Time between 2 checks of Run. Use 0 for DefaultInterval
+	Diff      bool          // Fetch the HTML diff of each change with action=compare
+	StatePath string        // JSON file where the watch state is saved after each check. Empty to keep it in memory
+	OnError   func(error)   // Called with the error of each failed check of Run. Use nil to ignore them
+}
+
+// Last known version of a page
+type PageState struct {
+	Title   string    `json:"title"` // Title of the page as normalized by the API
+	RevID   int       `json:"revid"` // 0 if the page does not exist
+	Touched time.Time `json:"touched"`
+}
+
+// A change detected by a Watcher
+type Change struct {
+	Title      string    `json:"title"`     // The watched title
+	OldRevID   int       `json:"old_revid"` // 0 if the page is created
+	NewRevID   int       `json:"new_revid"` // 0 if the page is deleted
+	OldTouched time.Time `json:"old_touched"`
+	NewTouched time.Time `json:"new_touched"`
+	Diff       string    `json:"diff"` // HTML table rows of the diff, with Options.Diff
+}
+
+// Watch a set of pages and call a handler when one of them changes
+type Watcher struct {
+	opts    Options
+	handler func(Change)
+	pages   map[string]*PageState // nil state: the page is not checked yet
+	lock    sync.Mutex
+}
+
+/*
+Make a watcher calling `handler` for every change. The state saved at opts.StatePath is loaded, if any
+*/
+func New(opts Options, handler func(Change)) (*Watcher, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	w := &Watcher{opts: opts, handler: handler, pages: map[string]*PageState{}}
+	if opts.StatePath == "" {
+		return w, nil
+	}
+	data, err := ioutil.ReadFile(opts.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := map[string]*PageState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	for title, s := range state {
+		w.pages[normalize(title)] = s
+	}
+	return w, nil
+}
+
+func normalize(title string) string {
+	return strings.ReplaceAll(strings.TrimSpace(title), "_", " ")
+}
+
+/*
+Start watching pages. Their current version is recorded by the next check, without calling the handler
+*/
+func (w *Watcher) Add(titles ...string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, title := range titles {
+		if _, ok := w.pages[normalize(title)]; !ok {
+			w.pages[normalize(title)] = nil
+		}
+	}
+}
+
+/*
+Stop watching pages
+*/
+func (w *Watcher) Remove(titles ...string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, title := range titles {
+		delete(w.pages, normalize(title))
+	}
+}
+
+/*
+Return the watched titles, sorted
+*/
+func (w *Watcher) Titles() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	titles := make([]string, 0, len(w.pages))
+	for title := range w.pages {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+/*
+Return the last known version of a page, and whether it is checked yet
+*/
+func (w *Watcher) State(title string) (PageState, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	s := w.pages[normalize(title)]
+	if s == nil {
+		return PageState{}, false
+	}
+	return *s, true
+}
+
+/*
+Check every watched page once, in batches of 50 titles.
+
+For each changed page the cache entries of the page are invalidated, then the handler is called
+*/
+func (w *Watcher) Check() error {
+	titles := w.Titles()
+	for start := 0; start < len(titles); start += batchSize {
+		end := start + batchSize
+		if end > len(titles) {
+			end = len(titles)
+		}
+		current, err := fetchStates(titles[start:end])
+		if err != nil {
+			return err
+		}
+		for _, title := range titles[start:end] {
+			if s, ok := current[title]; ok {
+				w.update(title, s)
+			}
+		}
+	}
+	return w.Save()
+}
+
+// Record the current state of a page and fire the handler if it changed
+func (w *Watcher) update(title string, current PageState) {
+	w.lock.Lock()
+	old, watched := w.pages[title]
+	if watched {
+		w.pages[title] = &current
+	}
+	w.lock.Unlock()
+	if !watched || old == nil || (old.RevID == current.RevID && old.Touched.Equal(current.Touched)) {
+		return
+	}
+	change := Change{
+		Title:      title,
+		OldRevID:   old.RevID,
+		NewRevID:   current.RevID,
+		OldTouched: old.Touched,
+		NewTouched: current.Touched,
+	}
+	utils.Cache.InvalidateTitle(title)
+	utils.Cache.InvalidateTitle(current.Title)
+	if w.opts.Diff && change.OldRevID != 0 && change.NewRevID != 0 && change.OldRevID != change.NewRevID {
+		// A failed diff does not hide the change
+		change.Diff, _ = GetDiff(change.OldRevID, change.NewRevID)
+	}
+	if w.handler != nil {
+		w.handler(change)
+	}
+}
+
+// Get the last revision and touched time of pages
+func fetchStates(titles []string) (map[string]PageState, error) {
+	args := map[string]string{
+		"action":         "query",
+		"prop":           "info",
+		"titles":         strings.Join(titles, "|"),
+		utils.NoCacheArg: "",
+	}
+	res, err := utils.WikiRequester(args)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error.Code != "" {
+		return nil, errors.New(res.Error.Info)
+	}
+	// Map the titles of the result to the watched titles
+	original := map[string]string{}
+	for _, n := range res.Query.Normalize {
+		original[n.To] = normalize(n.From)
+	}
+	result := map[string]PageState{}
+	for _, p := range res.Query.Page {
+		title := p.Title
+		if o, ok := original[title]; ok {
+			title = o
+		}
+		touched, _ := time.Parse(time.RFC3339, p.Touched)
+		result[normalize(title)] = PageState{Title: p.Title, RevID: p.LastRevid, Touched: touched}
+	}
+	return result, nil
+}
+
+/*
+Get the HTML diff between 2 revisions, as table rows
+*/
+func GetDiff(fromRev int, toRev int) (string, error) {
+	args := map[string]string{
+		"action":  "compare",
+		"fromrev": strconv.Itoa(fromRev),
+		"torev":   strconv.Itoa(toRev),
+		"prop":    "diff",
+	}
+	res, err := utils.WikiRequester(args)
+	if err != nil {
+		return "", err
+	}
+	if res.Error.Code != "" {
+		return "", errors.New(res.Error.Info)
+	}
+	return res.Compare.Body, nil
+}
+
+/*
+Save the watch state to opts.StatePath. Does nothing without a StatePath
+*/
+func (w *Watcher) Save() error {
+	if w.opts.StatePath == "" {
+		return nil
+	}
+	w.lock.Lock()
+	data, err := json.MarshalIndent(w.pages, "", "  ")
+	w.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves a truncated state
+	tmp := w.opts.StatePath + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.opts.StatePath)
+}
+
+/*
+Check the pages every opts.Interval until the context is done. A failed check is passed
+to opts.OnError, and the pages are checked again after the interval.
+
+Returns the context error
+*/
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		if err := w.Check(); err != nil && w.opts.OnError != nil {
+			w.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.opts.Interval):
+		}
+	}
+}
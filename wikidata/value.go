@@ -0,0 +1,221 @@
+package wikidata
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trietmn/go-wiki/models"
+)
+
+// Kinds of values
+const (
+	KindString          = "string"
+	KindMonolingualText = "monolingualtext"
+	KindItem            = "item"
+	KindTime            = "time"
+	KindQuantity        = "quantity"
+	KindCoordinate      = "coordinate"
+	KindNoValue         = "novalue"   // The property is known to have no value
+	KindSomeValue       = "somevalue" // The property has an unknown value
+	KindUnknown         = "unknown"   // Value type not supported by this package, see Value.Raw
+)
+
+// The value of a statement or a qualifier. Only the field matching Kind is set
+type Value struct {
+	Kind       string          `json:"kind"`
+	DataType   string          `json:"datatype"` // Datatype of the property, ex: "external-id", "url", "wikibase-item"
+	String     string          `json:"string"`   // Value of the string, url, external-id... and monolingualtext datatypes
+	Language   string          `json:"language"` // Language of a monolingualtext
+	Item       string          `json:"item"`     // ID of the entity, ex: "Q5"
+	Time       Time            `json:"time"`
+	Quantity   Quantity        `json:"quantity"`
+	Coordinate Coordinate      `json:"coordinate"`
+	Raw        json.RawMessage `json:"raw"`
+}
+
+// A point in time with a precision
+type Time struct {
+	Time      time.Time `json:"time"`      // Unknown month and day are set to 1
+	Raw       string    `json:"raw"`       // Wikibase format, ex: "+1815-12-10T00:00:00Z"
+	Precision int       `json:"precision"` // 9 for a year, 10 for a month, 11 for a day...
+	Calendar  string    `json:"calendar"`  // Entity ID of the calendar model, ex: "Q1985727" for the Gregorian calendar
+	Timezone  int       `json:"timezone"`  // Offset from UTC in minutes
+}
+
+// Precisions of the time values
+const (
+	PrecisionYear   = 9
+	PrecisionMonth  = 10
+	PrecisionDay    = 11
+	PrecisionHour   = 12
+	PrecisionMinute = 13
+	PrecisionSecond = 14
+)
+
+// An amount with an optional unit and bounds
+type Quantity struct {
+	Amount     float64  `json:"amount"`
+	RawAmount  string   `json:"rawamount"` // Decimal string kept for exactness, ex: "+1815"
+	Unit       string   `json:"unit"`      // Entity ID of the unit, empty when the quantity has no unit
+	UpperBound *float64 `json:"upperbound"`
+	LowerBound *float64 `json:"lowerbound"`
+}
+
+// A geographic coordinate
+type Coordinate struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Precision float64 `json:"precision"`
+	Globe     string  `json:"globe"` // Entity ID of the globe, ex: "Q2" for the Earth
+}
+
+// Return the entity ID at the end of a concept URI, ex: "http://www.wikidata.org/entity/Q2" gives "Q2"
+func entityID(uri string) string {
+	if i := strings.LastIndex(uri, "/"); i >= 0 {
+		return uri[i+1:]
+	}
+	return uri
+}
+
+func makeValue(snak models.WikidataSnak) Value {
+	value := Value{Kind: KindUnknown, DataType: snak.DataType, Raw: snak.DataValue.Value}
+	switch snak.SnakType {
+	case "novalue":
+		value.Kind = KindNoValue
+		return value
+	case "somevalue":
+		value.Kind = KindSomeValue
+		return value
+	}
+	raw := snak.DataValue.Value
+	switch snak.DataValue.Type {
+	case "string":
+		if json.Unmarshal(raw, &value.String) == nil {
+			value.Kind = KindString
+		}
+	case "monolingualtext":
+		var v struct {
+			Text     string `json:"text"`
+			Language string `json:"language"`
+		}
+		if json.Unmarshal(raw, &v) == nil {
+			value.Kind = KindMonolingualText
+			value.String = v.Text
+			value.Language = v.Language
+		}
+	case "wikibase-entityid":
+		var v struct {
+			ID        string `json:"id"`
+			NumericID int    `json:"numeric-id"`
+			Type      string `json:"entity-type"`
+		}
+		if json.Unmarshal(raw, &v) == nil {
+			value.Kind = KindItem
+			value.Item = v.ID
+			if value.Item == "" && v.Type == "item" {
+				value.Item = "Q" + strconv.Itoa(v.NumericID)
+			}
+		}
+	case "time":
+		var v struct {
+			Time          string `json:"time"`
+			Timezone      int    `json:"timezone"`
+			Precision     int    `json:"precision"`
+			CalendarModel string `json:"calendarmodel"`
+		}
+		if json.Unmarshal(raw, &v) == nil {
+			value.Kind = KindTime
+			value.Time = Time{
+				Time:      ParseTime(v.Time),
+				Raw:       v.Time,
+				Precision: v.Precision,
+				Calendar:  entityID(v.CalendarModel),
+				Timezone:  v.Timezone,
+			}
+		}
+	case "quantity":
+		var v struct {
+			Amount     string `json:"amount"`
+			Unit       string `json:"unit"`
+			UpperBound string `json:"upperBound"`
+			LowerBound string `json:"lowerBound"`
+		}
+		if json.Unmarshal(raw, &v) == nil {
+			value.Kind = KindQuantity
+			value.Quantity.RawAmount = v.Amount
+			value.Quantity.Amount, _ = strconv.ParseFloat(v.Amount, 64)
+			if v.Unit != "" && v.Unit != "1" {
+				value.Quantity.Unit = entityID(v.Unit)
+			}
+			if f, err := strconv.ParseFloat(v.UpperBound, 64); err == nil {
+				value.Quantity.UpperBound = &f
+			}
+			if f, err := strconv.ParseFloat(v.LowerBound, 64); err == nil {
+				value.Quantity.LowerBound = &f
+			}
+		}
+	case "globecoordinate":
+		var v struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+			Precision float64 `json:"precision"`
+			Globe     string  `json:"globe"`
+		}
+		if json.Unmarshal(raw, &v) == nil {
+			value.Kind = KindCoordinate
+			value.Coordinate = Coordinate{
+				Latitude:  v.Latitude,
+				Longitude: v.Longitude,
+				Precision: v.Precision,
+				Globe:     entityID(v.Globe),
+			}
+		}
+	}
+	return value
+}
+
+/*
+Parse a Wikibase time, ex: "+1815-12-10T00:00:00Z". Unknown months and days ("00") are set to 1,
+so "+1815-00-00T00:00:00Z" gives January 1st 1815. The negative years are BCE, which have no year 0:
+"-0044" is 44 BCE, the astronomical year -43. Returns the zero time if the format is wrong
+*/
+func ParseTime(s string) time.Time {
+	sign := 1
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	date := strings.SplitN(strings.TrimSuffix(s, "Z"), "T", 2)
+	if len(date) != 2 {
+		return time.Time{}
+	}
+	ymd := strings.Split(date[0], "-")
+	hms := strings.Split(date[1], ":")
+	if len(ymd) != 3 || len(hms) != 3 {
+		return time.Time{}
+	}
+	n := make([]int, 6)
+	for i, part := range append(ymd, hms...) {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}
+		}
+		n[i] = v
+	}
+	if n[1] == 0 {
+		n[1] = 1
+	}
+	if n[2] == 0 {
+		n[2] = 1
+	}
+	year := n[0]
+	if sign < 0 {
+		year = 1 - n[0]
+	}
+	return time.Date(year, time.Month(n[1]), n[2], n[3], n[4], n[5], 0, time.UTC)
+}
@@ -0,0 +1,211 @@
+package wikidata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/trietmn/go-wiki/models"
+	"github.com/trietmn/go-wiki/utils"
+)
+
+var (
+	// Wikibase API used to fetch the entities. Point it to another Wikibase, or to a local server in tests
+	APIURL string = "https://www.wikidata.org/w/api.php"
+)
+
+// Max number of entities in a single wbgetentities request
+const maxEntitiesPerQuery = 50
+
+// Ranks of the statements
+const (
+	RankPreferred  = "preferred"
+	RankNormal     = "normal"
+	RankDeprecated = "deprecated"
+)
+
+// A Wikibase item or property
+type Entity struct {
+	ID           string              `json:"id"`
+	Type         string              `json:"type"`
+	Labels       map[string]string   `json:"labels"`       // Label by language code
+	Descriptions map[string]string   `json:"descriptions"` // Description by language code
+	Aliases      map[string][]string `json:"aliases"`      // Aliases by language code
+	Sitelinks    map[string]Sitelink `json:"sitelinks"`    // Sitelink by site, ex: "enwiki"
+	Claims       map[string][]Claim  `json:"claims"`       // Statements by property ID, ex: "P569"
+}
+
+// A link from an entity to a page of a wiki
+type Sitelink struct {
+	Site   string   `json:"site"`
+	Title  string   `json:"title"`
+	URL    string   `json:"url"`
+	Badges []string `json:"badges"`
+}
+
+// A statement about an entity
+type Claim struct {
+	ID         string             `json:"id"`
+	Property   string             `json:"property"`
+	Rank       string             `json:"rank"`
+	Value      Value              `json:"value"`
+	Qualifiers map[string][]Value `json:"qualifiers"` // Qualifier values by property ID
+}
+
+/*
+Get an entity by ID, ex: "Q7259".
+
+Keyword arguments:
+
+* id: The entity ID
+
+* languages: Only keep the labels, descriptions and aliases of these languages. Empty for all
+
+Return:
+
+* The entity
+
+* Error
+*/
+func GetEntity(id string, languages ...string) (Entity, error) {
+	entities, err := GetEntities([]string{id}, languages...)
+	if err != nil {
+		return Entity{}, err
+	}
+	entity, ok := entities[id]
+	if !ok {
+		return Entity{}, fmt.Errorf("entity %v not found", id)
+	}
+	return entity, nil
+}
+
+/*
+Get entities by ID. Missing entities are not in the result.
+
+Return a map from the entity IDs to the entities
+*/
+func GetEntities(ids []string, languages ...string) (map[string]Entity, error) {
+	result := map[string]Entity{}
+	for start := 0; start < len(ids); start += maxEntitiesPerQuery {
+		end := start + maxEntitiesPerQuery
+		if end > len(ids) {
+			end = len(ids)
+		}
+		args := map[string]string{
+			"action": "wbgetentities",
+			"format": "json",
+			"ids":    strings.Join(ids[start:end], "|"),
+			"props":  "info|labels|descriptions|aliases|claims|sitelinks/urls",
+		}
+		if len(languages) > 0 {
+			args["languages"] = strings.Join(languages, "|")
+		}
+		var res models.WikidataResult
+		err := utils.RequestJSON(APIURL, args, &res)
+		if err != nil {
+			return result, err
+		}
+		if res.Error.Code != "" {
+			return result, errors.New(res.Error.Info)
+		}
+		for id, v := range res.Entities {
+			if v.Missing != nil {
+				continue
+			}
+			result[id] = makeEntity(v)
+		}
+	}
+	return result, nil
+}
+
+func makeEntity(v models.WikidataEntity) Entity {
+	entity := Entity{
+		ID:           v.ID,
+		Type:         v.Type,
+		Labels:       map[string]string{},
+		Descriptions: map[string]string{},
+		Aliases:      map[string][]string{},
+		Sitelinks:    map[string]Sitelink{},
+		Claims:       map[string][]Claim{},
+	}
+	for lang, t := range v.Labels {
+		entity.Labels[lang] = t.Value
+	}
+	for lang, t := range v.Descriptions {
+		entity.Descriptions[lang] = t.Value
+	}
+	for lang, aliases := range v.Aliases {
+		for _, t := range aliases {
+			entity.Aliases[lang] = append(entity.Aliases[lang], t.Value)
+		}
+	}
+	for site, s := range v.Sitelinks {
+		entity.Sitelinks[site] = Sitelink{Site: s.Site, Title: s.Title, URL: s.URL, Badges: s.Badges}
+	}
+	for property, statements := range v.Claims {
+		for _, s := range statements {
+			claim := Claim{
+				ID:       s.ID,
+				Property: property,
+				Rank:     s.Rank,
+				Value:    makeValue(s.MainSnak),
+			}
+			if len(s.Qualifiers) > 0 {
+				claim.Qualifiers = map[string][]Value{}
+				for qp, snaks := range s.Qualifiers {
+					for _, snak := range snaks {
+						claim.Qualifiers[qp] = append(claim.Qualifiers[qp], makeValue(snak))
+					}
+				}
+			}
+			entity.Claims[property] = append(entity.Claims[property], claim)
+		}
+	}
+	return entity
+}
+
+/*
+Label of the entity in language `lang`, empty if there is none
+*/
+func (entity Entity) Label(lang string) string {
+	return entity.Labels[lang]
+}
+
+/*
+Description of the entity in language `lang`, empty if there is none
+*/
+func (entity Entity) Description(lang string) string {
+	return entity.Descriptions[lang]
+}
+
+/*
+Return the best statements of a property: the preferred ones if there are, else the normal ones.
+Deprecated statements are never returned
+*/
+func (entity Entity) BestClaims(property string) []Claim {
+	preferred := []Claim{}
+	normal := []Claim{}
+	for _, c := range entity.Claims[property] {
+		switch c.Rank {
+		case RankPreferred:
+			preferred = append(preferred, c)
+		case RankNormal:
+			normal = append(normal, c)
+		}
+	}
+	if len(preferred) > 0 {
+		return preferred
+	}
+	return normal
+}
+
+/*
+Return the value of the first best statement of a property, and whether there is one
+*/
+func (entity Entity) Value(property string) (Value, bool) {
+	claims := entity.BestClaims(property)
+	if len(claims) == 0 {
+		return Value{}, false
+	}
+	return claims[0].Value, true
+}
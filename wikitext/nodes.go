@@ -0,0 +1,479 @@
+package wikitext
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A piece of the wikitext AST.
+// String serializes the node back to wikitext, Text returns its plain text rendering
+type Node interface {
+	String() string
+	Text() string
+}
+
+// A sequence of nodes
+type Nodes []Node
+
+// Plain text of a page, not touched by any other markup
+type Text struct {
+	Value string
+}
+
+// HTML comment: <!-- Contents -->
+type Comment struct {
+	Contents string
+	Closed   bool // False when the comment runs to the end of the text without "-->"
+}
+
+// Template parameter: {{{Name|Default}}}
+type Argument struct {
+	Name       Nodes
+	Default    Nodes
+	HasDefault bool
+}
+
+// Template transclusion: {{Name|positional|key=value}}
+type Template struct {
+	Name   Nodes
+	Params []*Parameter
+}
+
+// A positional or named parameter of a template
+type Parameter struct {
+	Name    Nodes // Empty for positional parameters
+	Value   Nodes
+	Showkey bool // True when the parameter is written as key=value
+}
+
+// Internal link: [[Target|Label]]
+type Wikilink struct {
+	Target Nodes
+	Label  Nodes
+	Piped  bool // True when the link has a "|" separator, even if Label is empty
+}
+
+// External link, either bracketed [URL Label] or a bare URL
+type ExternalLink struct {
+	URL       string
+	Label     Nodes
+	Separator string // Whitespace between the URL and the label
+	Brackets  bool
+}
+
+// HTML or extension tag such as <ref>, <div> or <br />
+type Tag struct {
+	Name        string
+	Attrs       string // Raw attribute string, including the leading whitespace
+	Contents    Nodes
+	SelfClosing bool   // <name ... />
+	Close       string // The raw closing tag, empty for self-closing or unclosed tags
+}
+
+// Section heading: == Title ==
+type Heading struct {
+	Level int
+	Title Nodes
+}
+
+// A block of inline content delimited by blank lines or other blocks
+type Paragraph struct {
+	Contents Nodes
+}
+
+// Consecutive list lines
+type List struct {
+	Items []*ListItem
+}
+
+// A single list line. Marker is the leading run of "*", "#", ":" and ";"
+type ListItem struct {
+	Marker   string
+	Contents Nodes
+}
+
+// Wikitable: {| ... |}
+type Table struct {
+	Attrs   string // Everything between "{|" and the first row, caption or cell
+	Caption *TableCaption
+	Rows    []*TableRow
+	Close   string // The raw "|}" line, including the preceding newline
+}
+
+// Table caption: |+ Contents
+type TableCaption struct {
+	Lead     string
+	Attrs    string
+	Contents Nodes
+}
+
+// Table row. The first row of a table may have an empty Lead when it is not opened by "|-"
+type TableRow struct {
+	Lead  string
+	Attrs string
+	Cells []*TableCell
+}
+
+// Table data or header cell
+type TableCell struct {
+	Lead     string // The raw separator: "\n|", "||", "\n!" or "!!"
+	Header   bool
+	Attrs    string // Raw attributes, followed by "|" when not empty
+	Contents Nodes
+}
+
+var (
+	emphasisRegex = regexp.MustCompile(`''+`)
+	voidTags      = []string{"br", "hr", "wbr", "img", "meta", "link", "input", "col", "area", "source"}
+	rawTags       = []string{"nowiki", "pre", "math", "chem", "ce", "syntaxhighlight", "source", "score", "timeline", "templatedata", "graph", "mapframe", "maplink", "hiero", "inputbox", "categorytree"}
+	// Namespaces of links that are not rendered inline
+	hiddenLinkPrefixes = []string{"file:", "image:", "category:"}
+)
+
+/*
+Serialize the nodes back to wikitext
+*/
+func (nodes Nodes) String() string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(n.String())
+	}
+	return b.String()
+}
+
+/*
+Plain text rendering of the nodes, without markup, templates, comments or references
+*/
+func (nodes Nodes) Text() string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(n.Text())
+	}
+	return b.String()
+}
+
+// Append text to nodes, merging it with the last node when that is a Text
+func (nodes Nodes) appendText(s string) Nodes {
+	if s == "" {
+		return nodes
+	}
+	if len(nodes) > 0 {
+		if t, ok := nodes[len(nodes)-1].(*Text); ok {
+			t.Value += s
+			return nodes
+		}
+	}
+	return append(nodes, &Text{Value: s})
+}
+
+func (t *Text) String() string {
+	return t.Value
+}
+
+func (t *Text) Text() string {
+	return emphasisRegex.ReplaceAllString(t.Value, "")
+}
+
+func (c *Comment) String() string {
+	if !c.Closed {
+		return "<!--" + c.Contents
+	}
+	return "<!--" + c.Contents + "-->"
+}
+
+func (c *Comment) Text() string {
+	return ""
+}
+
+func (a *Argument) String() string {
+	if a.HasDefault {
+		return "{{{" + a.Name.String() + "|" + a.Default.String() + "}}}"
+	}
+	return "{{{" + a.Name.String() + "}}}"
+}
+
+func (a *Argument) Text() string {
+	return a.Default.Text()
+}
+
+func (t *Template) String() string {
+	var b strings.Builder
+	b.WriteString("{{")
+	b.WriteString(t.Name.String())
+	for _, p := range t.Params {
+		b.WriteString("|")
+		b.WriteString(p.String())
+	}
+	b.WriteString("}}")
+	return b.String()
+}
+
+func (t *Template) Text() string {
+	return ""
+}
+
+/*
+Template name with the whitespace trimmed, underscores replaced by spaces
+and the first letter capitalized, as MediaWiki resolves it
+*/
+func (t *Template) TemplateName() string {
+	return NormalizeTitle(t.Name.String())
+}
+
+/*
+Return the parameter with the given name. Positional parameters are named "1", "2", ...
+
+Returns nil if the parameter does not exist. When a name is repeated the last one wins, like MediaWiki
+*/
+func (t *Template) Get(name string) *Parameter {
+	name = strings.TrimSpace(name)
+	var res *Parameter
+	position := 0
+	for _, p := range t.Params {
+		key := ""
+		if p.Showkey {
+			key = strings.TrimSpace(p.Name.String())
+		} else {
+			position++
+			key = strconv.Itoa(position)
+		}
+		if key == name {
+			res = p
+		}
+	}
+	return res
+}
+
+/*
+Return true if the template has a parameter with the given name
+*/
+func (t *Template) Has(name string) bool {
+	return t.Get(name) != nil
+}
+
+/*
+Set the value of parameter `name`, adding it at the end of the template if it does not exist.
+The value is parsed as wikitext. The whitespace around an existing value is kept
+*/
+func (t *Template) Set(name string, value string) {
+	if p := t.Get(name); p != nil {
+		old := p.Value.String()
+		lead := old[:len(old)-len(strings.TrimLeft(old, " \t\n"))]
+		trail := old[len(strings.TrimRight(old, " \t\n")):]
+		if strings.TrimSpace(old) == "" {
+			trail = ""
+		}
+		p.Value = ParseInline(lead + value + trail)
+		return
+	}
+	t.Params = append(t.Params, &Parameter{Name: Nodes{&Text{Value: name}}, Value: ParseInline(value), Showkey: true})
+}
+
+/*
+Remove the parameter `name` from the template. Returns false if it does not exist
+*/
+func (t *Template) Remove(name string) bool {
+	p := t.Get(name)
+	if p == nil {
+		return false
+	}
+	for i, v := range t.Params {
+		if v == p {
+			t.Params = append(t.Params[:i], t.Params[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (p *Parameter) String() string {
+	if p.Showkey {
+		return p.Name.String() + "=" + p.Value.String()
+	}
+	return p.Value.String()
+}
+
+func (p *Parameter) Text() string {
+	return strings.TrimSpace(p.Value.Text())
+}
+
+/*
+Trimmed parameter name. Empty for positional parameters
+*/
+func (p *Parameter) Key() string {
+	return strings.TrimSpace(p.Name.String())
+}
+
+func (l *Wikilink) String() string {
+	if l.Piped {
+		return "[[" + l.Target.String() + "|" + l.Label.String() + "]]"
+	}
+	return "[[" + l.Target.String() + "]]"
+}
+
+func (l *Wikilink) Text() string {
+	target := strings.TrimSpace(l.Target.String())
+	lower := strings.ToLower(target)
+	for _, prefix := range hiddenLinkPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return ""
+		}
+	}
+	if l.Piped && len(l.Label) > 0 {
+		return l.Label.Text()
+	}
+	return strings.TrimPrefix(l.Target.Text(), ":")
+}
+
+/*
+Normalized title of the linked page, without the section fragment
+*/
+func (l *Wikilink) Title() string {
+	target := l.Target.String()
+	if i := strings.Index(target, "#"); i >= 0 {
+		target = target[:i]
+	}
+	return NormalizeTitle(strings.TrimPrefix(strings.TrimSpace(target), ":"))
+}
+
+func (l *ExternalLink) String() string {
+	if !l.Brackets {
+		return l.URL
+	}
+	return "[" + l.URL + l.Separator + l.Label.String() + "]"
+}
+
+func (l *ExternalLink) Text() string {
+	if !l.Brackets {
+		return l.URL
+	}
+	return l.Label.Text()
+}
+
+func (t *Tag) String() string {
+	if t.SelfClosing {
+		return "<" + t.Name + t.Attrs + "/>"
+	}
+	return "<" + t.Name + t.Attrs + ">" + t.Contents.String() + t.Close
+}
+
+func (t *Tag) Text() string {
+	switch strings.ToLower(t.Name) {
+	case "ref", "references":
+		return ""
+	case "br":
+		return "\n"
+	}
+	return t.Contents.Text()
+}
+
+/*
+Return the value of attribute `name` of the tag, and whether it exists
+*/
+func (t *Tag) Attr(name string) (string, bool) {
+	v, ok := ParseAttrs(t.Attrs)[strings.ToLower(name)]
+	return v, ok
+}
+
+func (h *Heading) String() string {
+	marks := strings.Repeat("=", h.Level)
+	return marks + h.Title.String() + marks
+}
+
+func (h *Heading) Text() string {
+	return strings.TrimSpace(h.Title.Text())
+}
+
+func (p *Paragraph) String() string {
+	return p.Contents.String()
+}
+
+func (p *Paragraph) Text() string {
+	return p.Contents.Text()
+}
+
+func (l *List) String() string {
+	items := make([]string, len(l.Items))
+	for i, item := range l.Items {
+		items[i] = item.String()
+	}
+	return strings.Join(items, "\n")
+}
+
+func (l *List) Text() string {
+	items := make([]string, len(l.Items))
+	for i, item := range l.Items {
+		items[i] = item.Text()
+	}
+	return strings.Join(items, "\n")
+}
+
+func (i *ListItem) String() string {
+	return i.Marker + i.Contents.String()
+}
+
+func (i *ListItem) Text() string {
+	return strings.TrimSpace(i.Contents.Text())
+}
+
+func (t *Table) String() string {
+	var b strings.Builder
+	b.WriteString("{|")
+	b.WriteString(t.Attrs)
+	if t.Caption != nil {
+		b.WriteString(t.Caption.String())
+	}
+	for _, row := range t.Rows {
+		b.WriteString(row.String())
+	}
+	b.WriteString(t.Close)
+	return b.String()
+}
+
+func (t *Table) Text() string {
+	rows := make([]string, 0, len(t.Rows)+1)
+	if t.Caption != nil {
+		rows = append(rows, t.Caption.Text())
+	}
+	for _, row := range t.Rows {
+		if len(row.Cells) > 0 {
+			rows = append(rows, row.Text())
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (c *TableCaption) String() string {
+	return c.Lead + c.Attrs + c.Contents.String()
+}
+
+func (c *TableCaption) Text() string {
+	return strings.TrimSpace(c.Contents.Text())
+}
+
+func (r *TableRow) String() string {
+	var b strings.Builder
+	b.WriteString(r.Lead)
+	b.WriteString(r.Attrs)
+	for _, cell := range r.Cells {
+		b.WriteString(cell.String())
+	}
+	return b.String()
+}
+
+func (r *TableRow) Text() string {
+	cells := make([]string, len(r.Cells))
+	for i, cell := range r.Cells {
+		cells[i] = cell.Text()
+	}
+	return strings.Join(cells, "\t")
+}
+
+func (c *TableCell) String() string {
+	return c.Lead + c.Attrs + c.Contents.String()
+}
+
+func (c *TableCell) Text() string {
+	return strings.TrimSpace(c.Contents.Text())
+}
@@ -0,0 +1,459 @@
+package wikitext
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/trietmn/go-wiki/utils"
+)
+
+var (
+	tagOpenRegex  = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9]*)(\s[^<>]*?|)(/?)>`)
+	attrRegex     = regexp.MustCompile(`([A-Za-z_:][-A-Za-z0-9_:.]*)\s*(?:=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	extSchemeRegx = regexp.MustCompile(`(?i)^(https?:|ftp:|mailto:|//)`)
+	bareURLRegex  = regexp.MustCompile(`(?i)^https?://[^\s<>\[\]{}|"]+`)
+)
+
+// Return true if the parser has to stop at the current position
+type stopFunc func(p *parser) bool
+
+type parser struct {
+	src        string
+	pos        int
+	tableDepth int
+}
+
+/*
+Parse wikitext into a tree of block nodes (headings, paragraphs, lists and tables)
+containing inline nodes (links, templates, tags, comments and text).
+
+Parsing never fails: markup that cannot be matched is kept as Text,
+so Parse(s).String() == s for any input
+*/
+func Parse(src string) Nodes {
+	p := &parser{src: src}
+	return p.parseBlocks()
+}
+
+/*
+Parse wikitext into inline nodes only, without headings, paragraphs, lists or tables.
+Useful for template values and link labels
+*/
+func ParseInline(src string) Nodes {
+	p := &parser{src: src}
+	return p.parseInline(nil)
+}
+
+/*
+Normalize a page title the way MediaWiki does: trim the whitespace,
+replace underscores by spaces and capitalize the first letter
+*/
+func NormalizeTitle(title string) string {
+	title = strings.TrimSpace(strings.ReplaceAll(title, "_", " "))
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return title
+	}
+	r := []rune(title)
+	return strings.ToUpper(string(r[0])) + string(r[1:])
+}
+
+/*
+Parse a raw HTML attribute string into a map of lowercased names to values
+*/
+func ParseAttrs(s string) map[string]string {
+	res := map[string]string{}
+	for _, m := range attrRegex.FindAllStringSubmatch(s, -1) {
+		value := m[2]
+		if value == "" {
+			value = m[3]
+		}
+		if value == "" {
+			value = m[4]
+		}
+		res[strings.ToLower(m[1])] = value
+	}
+	return res
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) atLineStart() bool {
+	return p.pos == 0 || p.src[p.pos-1] == '\n'
+}
+
+// Return the end of the line starting at i (the index of "\n" or len(src))
+func (p *parser) lineEnd(i int) int {
+	end := strings.IndexByte(p.src[i:], '\n')
+	if end == -1 {
+		return len(p.src)
+	}
+	return i + end
+}
+
+// Return the index of the first character that is not a space or a tab from i
+func (p *parser) skipSpaces(i int) int {
+	for i < len(p.src) && (p.src[i] == ' ' || p.src[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func stopAt(tokens ...string) stopFunc {
+	return func(p *parser) bool {
+		for _, t := range tokens {
+			if p.hasPrefix(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (p *parser) parseBlocks() Nodes {
+	nodes := Nodes{}
+	for !p.eof() {
+		if p.src[p.pos] == '\n' {
+			nodes = nodes.appendText("\n")
+			p.pos++
+			continue
+		}
+		if level, end := p.headingAt(p.pos); level > 0 {
+			line := p.src[p.pos:end]
+			trimmed := strings.TrimRight(line, " \t")
+			nodes = append(nodes, &Heading{Level: level, Title: ParseInline(trimmed[level : len(trimmed)-level])})
+			nodes = nodes.appendText(line[len(trimmed):])
+			p.pos = end
+			continue
+		}
+		if strings.ContainsRune("*#:;", rune(p.src[p.pos])) {
+			nodes = append(nodes, p.parseList())
+			continue
+		}
+		if i := p.skipSpaces(p.pos); strings.HasPrefix(p.src[i:], "{|") {
+			nodes = nodes.appendText(p.src[p.pos:i])
+			p.pos = i
+			nodes = append(nodes, p.parseTable())
+			continue
+		}
+		nodes = append(nodes, &Paragraph{Contents: p.parseInline(paragraphEnd)})
+	}
+	return nodes
+}
+
+// Return the level of the heading on the line starting at i and the end of the line, or 0
+func (p *parser) headingAt(i int) (int, int) {
+	end := p.lineEnd(i)
+	line := strings.TrimRight(p.src[i:end], " \t")
+	if !strings.HasPrefix(line, "=") {
+		return 0, end
+	}
+	lead := len(line) - len(strings.TrimLeft(line, "="))
+	trail := len(line) - len(strings.TrimRight(line, "="))
+	level := lead
+	if trail < level {
+		level = trail
+	}
+	if level > 6 {
+		level = 6
+	}
+	if len(line) <= 2*level {
+		return 0, end
+	}
+	return level, end
+}
+
+// Paragraphs end before a blank line or a line starting another block
+func paragraphEnd(p *parser) bool {
+	if p.src[p.pos] != '\n' {
+		return false
+	}
+	next := p.pos + 1
+	if next >= len(p.src) || p.src[next] == '\n' {
+		return true
+	}
+	if strings.ContainsRune("*#:;", rune(p.src[next])) {
+		return true
+	}
+	if level, _ := p.headingAt(next); level > 0 {
+		return true
+	}
+	return strings.HasPrefix(p.src[p.skipSpaces(next):], "{|")
+}
+
+func atNewline(p *parser) bool {
+	return p.src[p.pos] == '\n'
+}
+
+func (p *parser) parseList() *List {
+	list := &List{}
+	for {
+		start := p.pos
+		for !p.eof() && strings.ContainsRune("*#:;", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		item := &ListItem{Marker: p.src[start:p.pos]}
+		item.Contents = p.parseInline(atNewline)
+		list.Items = append(list.Items, item)
+		if !p.hasPrefix("\n") || p.pos+1 >= len(p.src) || !strings.ContainsRune("*#:;", rune(p.src[p.pos+1])) {
+			return list
+		}
+		p.pos++
+	}
+}
+
+/*
+Parse inline nodes until `stop` returns true or the end of the input
+*/
+func (p *parser) parseInline(stop stopFunc) Nodes {
+	nodes := Nodes{}
+	start := p.pos
+	for !p.eof() {
+		if stop != nil && stop(p) {
+			break
+		}
+		at := p.pos
+		node := p.parseConstruct()
+		if node == nil {
+			p.pos++
+			continue
+		}
+		nodes = nodes.appendText(p.src[start:at])
+		nodes = append(nodes, node)
+		start = p.pos
+	}
+	return nodes.appendText(p.src[start:p.pos])
+}
+
+/*
+Try to parse a node at the current position.
+Returns nil and leaves the position unchanged if there is none
+*/
+func (p *parser) parseConstruct() Node {
+	switch p.src[p.pos] {
+	case '<':
+		if p.hasPrefix("<!--") {
+			return p.parseComment()
+		}
+		return p.parseTag()
+	case '{':
+		if p.hasPrefix("{{{") {
+			if node := p.parseArgument(); node != nil {
+				return node
+			}
+		}
+		if p.hasPrefix("{{") {
+			return p.parseTemplate()
+		}
+		if p.tableDepth > 0 && p.hasPrefix("{|") && p.atLineStart() {
+			return p.parseTable()
+		}
+	case '[':
+		if p.hasPrefix("[[") {
+			return p.parseWikilink()
+		}
+		return p.parseExternalLink()
+	case 'h', 'H':
+		if p.pos == 0 || !isWordChar(p.src[p.pos-1]) {
+			return p.parseBareURL()
+		}
+	}
+	return nil
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *parser) parseComment() Node {
+	p.pos += len("<!--")
+	end := strings.Index(p.src[p.pos:], "-->")
+	if end == -1 {
+		c := &Comment{Contents: p.src[p.pos:]}
+		p.pos = len(p.src)
+		return c
+	}
+	c := &Comment{Contents: p.src[p.pos : p.pos+end], Closed: true}
+	p.pos += end + len("-->")
+	return c
+}
+
+func (p *parser) parseArgument() Node {
+	start := p.pos
+	p.pos += 3
+	arg := &Argument{Name: p.parseInline(stopAt("|", "}}}"))}
+	if p.hasPrefix("|") {
+		p.pos++
+		arg.HasDefault = true
+		arg.Default = p.parseInline(stopAt("}}}"))
+	}
+	if !p.hasPrefix("}}}") {
+		p.pos = start
+		return nil
+	}
+	p.pos += 3
+	return arg
+}
+
+func (p *parser) parseTemplate() Node {
+	start := p.pos
+	p.pos += 2
+	t := &Template{Name: p.parseInline(stopAt("|", "}}"))}
+	for {
+		if p.hasPrefix("}}") {
+			p.pos += 2
+			return t
+		}
+		if !p.hasPrefix("|") {
+			p.pos = start
+			return nil
+		}
+		p.pos++
+		param := &Parameter{}
+		value := p.parseInline(stopAt("|", "}}", "="))
+		if p.hasPrefix("=") {
+			p.pos++
+			param.Name = value
+			param.Showkey = true
+			value = p.parseInline(stopAt("|", "}}"))
+		}
+		param.Value = value
+		t.Params = append(t.Params, param)
+	}
+}
+
+func (p *parser) parseWikilink() Node {
+	start := p.pos
+	p.pos += 2
+	link := &Wikilink{Target: p.parseInline(stopAt("|", "]]", "\n"))}
+	if p.hasPrefix("|") {
+		p.pos++
+		link.Piped = true
+		link.Label = p.parseInline(stopAt("]]"))
+	}
+	if !p.hasPrefix("]]") {
+		p.pos = start
+		return nil
+	}
+	p.pos += 2
+	return link
+}
+
+func (p *parser) parseExternalLink() Node {
+	start := p.pos
+	if !extSchemeRegx.MatchString(p.src[p.pos+1:]) {
+		return nil
+	}
+	p.pos++
+	end := p.pos
+	for end < len(p.src) && !strings.ContainsRune(" \t\n]", rune(p.src[end])) {
+		end++
+	}
+	link := &ExternalLink{URL: p.src[p.pos:end], Brackets: true}
+	p.pos = end
+	if sep := p.skipSpaces(p.pos); sep > p.pos {
+		link.Separator = p.src[p.pos:sep]
+		p.pos = sep
+		link.Label = p.parseInline(stopAt("]", "\n"))
+	}
+	if !p.hasPrefix("]") {
+		p.pos = start
+		return nil
+	}
+	p.pos++
+	return link
+}
+
+func (p *parser) parseBareURL() Node {
+	url := bareURLRegex.FindString(p.src[p.pos:])
+	if url == "" {
+		return nil
+	}
+	url = strings.TrimRight(url, ".,;:!?)'")
+	p.pos += len(url)
+	return &ExternalLink{URL: url}
+}
+
+func (p *parser) parseTag() Node {
+	m := tagOpenRegex.FindStringSubmatch(p.src[p.pos:])
+	if m == nil {
+		return nil
+	}
+	start := p.pos
+	tag := &Tag{Name: m[1], Attrs: m[2], SelfClosing: m[3] == "/"}
+	p.pos += len(m[0])
+	name := strings.ToLower(tag.Name)
+	if tag.SelfClosing || utils.Isin(voidTags, name) {
+		return tag
+	}
+	if utils.Isin(rawTags, name) {
+		i, n := findClosingTag(p.src[p.pos:], name)
+		if i < 0 {
+			return p.unclosedTag(tag, start+len(m[0]))
+		}
+		tag.Contents = Nodes{}.appendText(p.src[p.pos : p.pos+i])
+		tag.Close = p.src[p.pos+i : p.pos+i+n]
+		p.pos += i + n
+		return tag
+	}
+	closing := "</" + name
+	tag.Contents = p.parseInline(func(p *parser) bool {
+		return p.src[p.pos] == '<' && strings.HasPrefix(strings.ToLower(p.src[p.pos:min(len(p.src), p.pos+len(closing))]), closing)
+	})
+	if n := closingTagLen(p.src[p.pos:], name); n > 0 {
+		tag.Close = p.src[p.pos : p.pos+n]
+		p.pos += n
+		return tag
+	}
+	return p.unclosedTag(tag, start+len(m[0]))
+}
+
+// Length of the closing tag `</name>` at the start of s, in any case and with spaces before the `>`, or 0 if there is none.
+// The tags are scanned by hand, as a regexp for each tag name would be compiled for every tag of the page
+func closingTagLen(s string, name string) int {
+	if len(s) < len(name)+3 || !strings.HasPrefix(s, "</") || !strings.EqualFold(s[2:2+len(name)], name) {
+		return 0
+	}
+	i := 2 + len(name)
+	for i < len(s) && strings.IndexByte(" \t\n\f\r", s[i]) >= 0 {
+		i++
+	}
+	if i < len(s) && s[i] == '>' {
+		return i + 1
+	}
+	return 0
+}
+
+// Position and length of the first closing tag `</name>` in s, or -1 if there is none
+func findClosingTag(s string, name string) (int, int) {
+	for i := 0; ; i += 2 {
+		j := strings.Index(s[i:], "</")
+		if j < 0 {
+			return -1, 0
+		}
+		i += j
+		if n := closingTagLen(s[i:], name); n > 0 {
+			return i, n
+		}
+	}
+}
+
+// An opening tag without its closing tag is kept as a tag without contents
+func (p *parser) unclosedTag(tag *Tag, end int) Node {
+	tag.Contents = nil
+	p.pos = end
+	return tag
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+package wikitext
+
+import "strings"
+
+/*
+Parse a wikitable starting at "{|". Rows, captions and cells keep their raw separators
+so that the table serializes back to the exact same wikitext
+*/
+func (p *parser) parseTable() *Table {
+	p.tableDepth++
+	defer func() { p.tableDepth-- }()
+	p.pos += len("{|")
+	t := &Table{}
+	start := p.pos
+	for !p.eof() && !p.atTableLine() {
+		p.pos++
+	}
+	t.Attrs = p.src[start:p.pos]
+	var row *TableRow
+	for !p.eof() {
+		// The parser is at the "\n" before a line starting with "|" or "!"
+		marker := p.skipSpaces(p.pos + 1)
+		switch {
+		case strings.HasPrefix(p.src[marker:], "|}"):
+			t.Close = p.src[p.pos : marker+2]
+			p.pos = marker + 2
+			return t
+		case strings.HasPrefix(p.src[marker:], "|-"):
+			row = &TableRow{Lead: p.src[p.pos : marker+2]}
+			t.Rows = append(t.Rows, row)
+			p.pos = marker + 2
+			for !p.eof() && !p.atTableLine() {
+				p.pos++
+			}
+			row.Attrs = p.src[marker+2 : p.pos]
+		case strings.HasPrefix(p.src[marker:], "|+") && t.Caption == nil && len(t.Rows) == 0:
+			t.Caption = &TableCaption{Lead: p.src[p.pos : marker+2]}
+			p.pos = marker + 2
+			t.Caption.Attrs = p.parseCellAttrs(false)
+			t.Caption.Contents = p.parseInline(cellEnd(false))
+		default:
+			if row == nil {
+				row = &TableRow{}
+				t.Rows = append(t.Rows, row)
+			}
+			header := p.src[marker] == '!'
+			lead := p.src[p.pos : marker+1]
+			for {
+				p.pos += len(lead)
+				cell := &TableCell{Lead: lead, Header: header, Attrs: p.parseCellAttrs(header)}
+				cell.Contents = p.parseInline(cellEnd(header))
+				row.Cells = append(row.Cells, cell)
+				if p.hasPrefix("||") || (header && p.hasPrefix("!!")) {
+					lead = p.src[p.pos : p.pos+2]
+					continue
+				}
+				break
+			}
+		}
+	}
+	return t
+}
+
+// Return true if the parser is at the "\n" before a table line ("|", "!", "|-", "|+" or "|}")
+func (p *parser) atTableLine() bool {
+	if p.src[p.pos] != '\n' {
+		return false
+	}
+	i := p.skipSpaces(p.pos + 1)
+	return i < len(p.src) && (p.src[i] == '|' || p.src[i] == '!')
+}
+
+// Cells end at the next table line or at an inline "||" (or "!!" for header cells)
+func cellEnd(header bool) stopFunc {
+	return func(p *parser) bool {
+		if p.hasPrefix("||") || (header && p.hasPrefix("!!")) {
+			return true
+		}
+		return p.atTableLine()
+	}
+}
+
+/*
+Return the raw attributes of the cell at the current position, including the "|" that ends them,
+and move past them. Returns "" if the cell has no attributes
+*/
+func (p *parser) parseCellAttrs(header bool) string {
+	depth := 0
+	for i := p.pos; i < len(p.src) && p.src[i] != '\n'; i++ {
+		rest := p.src[i:]
+		switch {
+		case strings.HasPrefix(rest, "[[") || strings.HasPrefix(rest, "{{"):
+			depth++
+			i++
+		case strings.HasPrefix(rest, "]]") || strings.HasPrefix(rest, "}}"):
+			depth--
+			i++
+		case strings.HasPrefix(rest, "||") || (header && strings.HasPrefix(rest, "!!")):
+			return ""
+		case p.src[i] == '|' && depth <= 0:
+			attrs := p.src[p.pos : i+1]
+			p.pos = i + 1
+			return attrs
+		}
+	}
+	return ""
+}
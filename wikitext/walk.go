@@ -0,0 +1,185 @@
+package wikitext
+
+import "strings"
+
+/*
+Call fn for every node in the tree in depth-first order, including the nodes nested in
+template names and parameters, link targets and labels, tag contents and table cells.
+
+Children of a node are skipped when fn returns false
+*/
+func Walk(nodes Nodes, fn func(Node) bool) {
+	for _, n := range nodes {
+		walkNode(n, fn)
+	}
+}
+
+func walkNode(n Node, fn func(Node) bool) {
+	if !fn(n) {
+		return
+	}
+	switch v := n.(type) {
+	case *Argument:
+		Walk(v.Name, fn)
+		Walk(v.Default, fn)
+	case *Template:
+		Walk(v.Name, fn)
+		for _, p := range v.Params {
+			walkNode(p, fn)
+		}
+	case *Parameter:
+		Walk(v.Name, fn)
+		Walk(v.Value, fn)
+	case *Wikilink:
+		Walk(v.Target, fn)
+		Walk(v.Label, fn)
+	case *ExternalLink:
+		Walk(v.Label, fn)
+	case *Tag:
+		Walk(v.Contents, fn)
+	case *Heading:
+		Walk(v.Title, fn)
+	case *Paragraph:
+		Walk(v.Contents, fn)
+	case *List:
+		for _, item := range v.Items {
+			walkNode(item, fn)
+		}
+	case *ListItem:
+		Walk(v.Contents, fn)
+	case *Table:
+		if v.Caption != nil {
+			walkNode(v.Caption, fn)
+		}
+		for _, row := range v.Rows {
+			walkNode(row, fn)
+		}
+	case *TableCaption:
+		Walk(v.Contents, fn)
+	case *TableRow:
+		for _, cell := range v.Cells {
+			walkNode(cell, fn)
+		}
+	case *TableCell:
+		Walk(v.Contents, fn)
+	}
+}
+
+/*
+All the templates in the tree, including nested ones
+*/
+func (nodes Nodes) Templates() []*Template {
+	res := []*Template{}
+	Walk(nodes, func(n Node) bool {
+		if t, ok := n.(*Template); ok {
+			res = append(res, t)
+		}
+		return true
+	})
+	return res
+}
+
+/*
+All the templates named `name` (case insensitive on the first letter, like MediaWiki)
+*/
+func (nodes Nodes) TemplatesNamed(name string) []*Template {
+	name = NormalizeTitle(name)
+	res := []*Template{}
+	for _, t := range nodes.Templates() {
+		if t.TemplateName() == name {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+/*
+All the internal links in the tree
+*/
+func (nodes Nodes) Wikilinks() []*Wikilink {
+	res := []*Wikilink{}
+	Walk(nodes, func(n Node) bool {
+		if l, ok := n.(*Wikilink); ok {
+			res = append(res, l)
+		}
+		return true
+	})
+	return res
+}
+
+/*
+All the external links in the tree
+*/
+func (nodes Nodes) ExternalLinks() []*ExternalLink {
+	res := []*ExternalLink{}
+	Walk(nodes, func(n Node) bool {
+		if l, ok := n.(*ExternalLink); ok {
+			res = append(res, l)
+		}
+		return true
+	})
+	return res
+}
+
+/*
+All the tags named `name` (case insensitive). Use "" to get every tag
+*/
+func (nodes Nodes) Tags(name string) []*Tag {
+	res := []*Tag{}
+	Walk(nodes, func(n Node) bool {
+		if t, ok := n.(*Tag); ok && (name == "" || strings.EqualFold(t.Name, name)) {
+			res = append(res, t)
+		}
+		return true
+	})
+	return res
+}
+
+/*
+All the <ref> tags in the tree
+*/
+func (nodes Nodes) Refs() []*Tag {
+	return nodes.Tags("ref")
+}
+
+/*
+All the comments in the tree
+*/
+func (nodes Nodes) Comments() []*Comment {
+	res := []*Comment{}
+	Walk(nodes, func(n Node) bool {
+		if c, ok := n.(*Comment); ok {
+			res = append(res, c)
+		}
+		return true
+	})
+	return res
+}
+
+/*
+All the headings of the tree
+*/
+func (nodes Nodes) Headings() []*Heading {
+	res := []*Heading{}
+	Walk(nodes, func(n Node) bool {
+		if h, ok := n.(*Heading); ok {
+			res = append(res, h)
+		}
+		return true
+	})
+	return res
+}
+
+/*
+All the tables in the tree, including nested ones
+*/
+func (nodes Nodes) Tables() []*Table {
+	res := []*Table{}
+	Walk(nodes, func(n Node) bool {
+		if t, ok := n.(*Table); ok {
+			res = append(res, t)
+		}
+		return true
+	})
+	return res
+}